@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/erick785/services/common/wallet"
+)
+
+// 钱包 meta 中的键
+const (
+	metaAddressBook   = "address_book"   // 常用收款地址
+	metaWhitelistOnly = "whitelist_only" // 只允许转出到常用地址
+	metaWhitelistOff  = "whitelist_off"  // 关闭只允许常用地址的生效时间
+)
+
+// BookAddress 常用收款地址
+type BookAddress struct {
+	Address string `json:"address"` // 地址
+	Label   string `json:"label"`   // 备注
+	Time    int64  `json:"time"`    // 添加时间
+}
+
+// AddressBook 用户的常用地址
+func AddressBook(wlt *wallet.Wallet) []*BookAddress {
+	book := []*BookAddress{}
+	metaValue(wlt, metaAddressBook, &book)
+	return book
+}
+
+func setAddressBook(wlt *wallet.Wallet, book []*BookAddress) {
+	if wlt.Meta == nil {
+		wlt.Meta = make(map[string]interface{})
+	}
+	wlt.Meta[metaAddressBook] = book
+}
+
+// AddBookAddress 添加常用地址, 已存在时只更新备注, 由调用方保存钱包
+func AddBookAddress(wlt *wallet.Wallet, address string, label string) *BookAddress {
+	book := AddressBook(wlt)
+	address = strings.ToLower(address)
+	for _, entry := range book {
+		if entry.Address == address {
+			entry.Label = label
+			setAddressBook(wlt, book)
+			return entry
+		}
+	}
+	entry := &BookAddress{
+		Address: address,
+		Label:   label,
+		Time:    time.Now().Unix(),
+	}
+	setAddressBook(wlt, append(book, entry))
+	return entry
+}
+
+// RemoveBookAddress 删除常用地址, 由调用方保存钱包
+func RemoveBookAddress(wlt *wallet.Wallet, address string) bool {
+	book := AddressBook(wlt)
+	address = strings.ToLower(address)
+	for i, entry := range book {
+		if entry.Address == address {
+			setAddressBook(wlt, append(book[:i], book[i+1:]...))
+			return true
+		}
+	}
+	return false
+}
+
+// WhitelistOnly 是否只允许转出到常用地址, 申请关闭后在冷却期结束前仍生效
+func WhitelistOnly(wlt *wallet.Wallet) bool {
+	var only bool
+	metaValue(wlt, metaWhitelistOnly, &only)
+	var off int64
+	if only && metaValue(wlt, metaWhitelistOff, &off) && time.Now().Unix() >= off {
+		return false
+	}
+	return only
+}
+
+// WhitelistOffTime 关闭只允许常用地址的生效时间, 未申请关闭时为 0
+func WhitelistOffTime(wlt *wallet.Wallet) int64 {
+	var off int64
+	if WhitelistOnly(wlt) {
+		metaValue(wlt, metaWhitelistOff, &off)
+	}
+	return off
+}
+
+// SetWhitelistOnly 设置只允许转出到常用地址, 开启立即生效, 关闭在 delay 冷却期后生效,
+// 冷却期内重复关闭不延长; 由调用方保存钱包
+func SetWhitelistOnly(wlt *wallet.Wallet, only bool, delay time.Duration) {
+	if wlt.Meta == nil {
+		wlt.Meta = make(map[string]interface{})
+	}
+	if only || !WhitelistOnly(wlt) {
+		wlt.Meta[metaWhitelistOnly] = only
+		delete(wlt.Meta, metaWhitelistOff)
+		return
+	}
+	if WhitelistOffTime(wlt) == 0 {
+		wlt.Meta[metaWhitelistOff] = time.Now().Add(delay).Unix()
+	}
+}
+
+// DestinationError 收款地址不允许收款
+type DestinationError struct {
+	Address string
+	Reason  string
+}
+
+func (e *DestinationError) Error() string {
+	return fmt.Sprintf("address %s %s", e.Address, e.Reason)
+}
+
+// CheckDestination 检查收款地址: 新添加的常用地址在冷却期内不能收款,
+// 开启只允许常用地址时拒绝其它地址
+func CheckDestination(wlt *wallet.Wallet, to string, delay time.Duration) error {
+	to = strings.ToLower(to)
+	for _, entry := range AddressBook(wlt) {
+		if entry.Address != to {
+			continue
+		}
+		if available := time.Unix(entry.Time, 0).Add(delay); time.Now().Before(available) {
+			return &DestinationError{Address: to, Reason: fmt.Sprintf("in cooling-off period until %s", available.Format("2006-01-02 15:04:05"))}
+		}
+		return nil
+	}
+	if WhitelistOnly(wlt) && len(to) == 0 {
+		return &DestinationError{Address: to, Reason: "contract creation not allowed in whitelist-only mode"}
+	}
+	if WhitelistOnly(wlt) {
+		return &DestinationError{Address: to, Reason: "not in address book"}
+	}
+	return nil
+}
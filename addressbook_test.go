@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/erick785/services/common/wallet"
+)
+
+func TestCheckDestination(t *testing.T) {
+	to := "0x00000000000000000000000000000000000000bb"
+	added := func(ago time.Duration) func(wlt *wallet.Wallet) {
+		return func(wlt *wallet.Wallet) {
+			AddBookAddress(wlt, to, "").Time = time.Now().Add(-ago).Unix()
+		}
+	}
+	for _, tc := range []struct {
+		name      string
+		book      func(wlt *wallet.Wallet)
+		whitelist bool
+		to        string
+		allowed   bool
+	}{
+		{"any address", nil, false, to, true},
+		{"cooling-off", added(time.Minute), false, to, false},
+		{"cooling-off upper case", added(time.Minute), false, "0x00000000000000000000000000000000000000BB", false},
+		{"cooled", added(2 * time.Hour), false, to, true},
+		{"whitelist not in book", nil, true, to, false},
+		{"whitelist cooling-off", added(time.Minute), true, to, false},
+		{"whitelist in book", added(2 * time.Hour), true, to, true},
+		{"whitelist contract creation", nil, true, "", false},
+	} {
+		wlt := newTestWallet(t, "user")
+		if tc.book != nil {
+			tc.book(wlt)
+		}
+		SetWhitelistOnly(wlt, tc.whitelist, time.Hour)
+		err := CheckDestination(wlt, tc.to, time.Hour)
+		if _, ok := err.(*DestinationError); err != nil && !ok {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if allowed := err == nil; allowed != tc.allowed {
+			t.Errorf("%s: allowed %v (%v), expected %v", tc.name, allowed, err, tc.allowed)
+		}
+	}
+}
+
+func TestSetWhitelistOnly(t *testing.T) {
+	delay := time.Hour
+	type step struct {
+		only bool
+		want bool // 设置后是否生效
+		off  bool // 是否已申请关闭
+	}
+	for _, tc := range []struct {
+		name  string
+		steps []step
+	}{
+		{"enable", []step{{true, true, false}}},
+		{"disable when off", []step{{false, false, false}}},
+		{"disable after delay", []step{{true, true, false}, {false, true, true}}},
+		{"disable twice", []step{{true, true, false}, {false, true, true}, {false, true, true}}},
+		{"enable cancels disable", []step{{true, true, false}, {false, true, true}, {true, true, false}}},
+	} {
+		wlt := newTestWallet(t, "user")
+		var off int64
+		for i, s := range tc.steps {
+			SetWhitelistOnly(wlt, s.only, delay)
+			if got := WhitelistOnly(wlt); got != s.want {
+				t.Errorf("%s: step %d whitelist only %v, expected %v", tc.name, i, got, s.want)
+			}
+			offTime := WhitelistOffTime(wlt)
+			if (offTime != 0) != s.off {
+				t.Errorf("%s: step %d off time %d, expected off %v", tc.name, i, offTime, s.off)
+			}
+			//重复关闭不延长冷却期
+			if off != 0 && offTime != 0 && offTime != off {
+				t.Errorf("%s: step %d off time %d changed from %d", tc.name, i, offTime, off)
+			}
+			if s.off && time.Unix(offTime, 0).Before(time.Now().Add(delay-time.Minute)) {
+				t.Errorf("%s: step %d off time %d before delay", tc.name, i, offTime)
+			}
+			off = offTime
+		}
+	}
+
+	//冷却期结束后关闭生效
+	wlt := newTestWallet(t, "user")
+	SetWhitelistOnly(wlt, true, delay)
+	SetWhitelistOnly(wlt, false, 0)
+	if WhitelistOnly(wlt) || WhitelistOffTime(wlt) != 0 {
+		t.Errorf("whitelist only still on after delay")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/erick785/services/common/wallet"
+)
+
+// 钱包 meta 中的键
+const (
+	metaExports = "mnemonic_exports" // 助记词及私钥导出时间
+)
+
+// 审计操作
+const (
+	AuditExportMnemonic = "export_mnemonic" // 导出助记词
+	AuditExportDenied   = "export_denied"   // 导出助记词或私钥超过次数限制
+	AuditImportWallet   = "import_wallet"   // 导入钱包
+	AuditExportKeystore = "export_keystore" // 导出私钥
+	AuditImportKeystore = "import_keystore" // 导入私钥
+	AuditSetPassphrase  = "set_passphrase"  // 设置或取消口令
+	AuditImportXPub     = "import_xpub"     // 创建观察钱包
+)
+
+// exportWindow 助记词导出次数统计周期
+var exportWindow = 24 * time.Hour
+
+// Audit 敏感操作审计记录
+type Audit struct {
+	Phone  string `json:"phone"`  // 用户标识
+	Action string `json:"action"` // 操作
+	IP     string `json:"ip"`     // 请求来源
+	Detail string `json:"detail"` // 详情
+	Time   int64  `json:"time"`   // 操作时间
+}
+
+// CheckExport 统计周期内导出次数未达到 limit 时记录本次导出, 由调用方保存钱包
+func CheckExport(wlt *wallet.Wallet, limit int, now time.Time) error {
+	exports := []int64{}
+	metaValue(wlt, metaExports, &exports)
+	recent := []int64{}
+	for _, t := range exports {
+		if now.Sub(time.Unix(t, 0)) < exportWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= limit {
+		return fmt.Errorf("exported %d times in %v, limit %d", len(recent), exportWindow, limit)
+	}
+	if wlt.Meta == nil {
+		wlt.Meta = make(map[string]interface{})
+	}
+	wlt.Meta[metaExports] = append(recent, now.Unix())
+	return nil
+}
+
+// updateImported 记录导入私钥的地址并保存钱包, 已记录时不更新
+func updateImported(wltdb *wallet.Mysql, wlt *wallet.Wallet, address string) error {
+	if !wlt.AddImportedKey(address, time.Now().Unix()) {
+		return nil
+	}
+	return wltdb.UpdateWallet(wlt)
+}
+
+// setPassphrase 切换钱包口令并保存, id 为口令下的默认地址; 口令为空时取消口令
+func setPassphrase(wltdb *wallet.Mysql, wlt *wallet.Wallet, passphrase string, id string) error {
+	if len(passphrase) == 0 {
+		id = ""
+	}
+	wlt.SetPassphrase(id)
+	return wltdb.UpdateWallet(wlt)
+}
@@ -23,6 +23,7 @@ const (
 	methodGetBalance            = "Uranus.GetBalance"
 	methodGetTransactionCount   = "Uranus.GetNonce"
 	methodCall                  = "Uranus.Call"
+	methodEstimateGas           = "Uranus.EstimateGas"
 )
 
 // RPCClient rpc
@@ -88,6 +89,45 @@ func (client *RPCClient) GetRawMemPool(otxs map[string]*Transaction) ([]*Transac
 	return txs, nil
 }
 
+// GetPoolNonces 获取内存池中指定地址的交易序号 (nonce -> hash)
+func (client *RPCClient) GetPoolNonces(address string) (map[uint64]string, error) {
+	request := common.NewRPCRequest("2.0", methodTxPool)
+	jsonParsed, err := common.SendRPCRequst(client.RPCHost, request)
+	if err != nil {
+		return nil, fmt.Errorf("GetPoolNonces SendRPCRequst error --- %s", err)
+	}
+
+	if jsonParsed.Path("error").Data() != nil {
+		msg, _ := jsonParsed.Path("error").Data().(string)
+		return nil, fmt.Errorf("GetPoolNonces error --- %s", msg)
+	}
+
+	if /*value*/ _, ok := jsonParsed.Path("error.code").Data().(float64); ok /*&& value > 0*/ {
+		msg, _ := jsonParsed.Path("error.message").Data().(string)
+		return nil, fmt.Errorf("GetPoolNonces error --- %s", msg)
+	}
+
+	nonces := make(map[uint64]string)
+	for _, section := range []string{"pending", "queued"} {
+		children, _ := jsonParsed.S("result", section).ChildrenMap()
+		for _, child := range children {
+			tchildren, _ := child.ChildrenMap()
+			for _, tchild := range tchildren {
+				from, _ := tchild.Path("from").Data().(string)
+				if strings.Compare(strings.ToLower(from), strings.ToLower(address)) != 0 {
+					continue
+				}
+				nonce, _ := tchild.Path("nonce").Data().(string)
+				hash, _ := tchild.Path("hash").Data().(string)
+				ret := new(big.Int)
+				ret.UnmarshalJSON([]byte(nonce))
+				nonces[ret.Uint64()] = hash
+			}
+		}
+	}
+	return nonces, nil
+}
+
 // GetBlockByNumberJSON 获取指定高度的区块
 func (client *RPCClient) GetBlockByNumberJSON(number *big.Int, full bool) (interface{}, error) {
 	t := time.Now()
@@ -175,6 +215,120 @@ func (client *RPCClient) GetTransaction(hash string) (*Transaction, error) {
 	return client.decodeTransactionJSON(jsonParsed.Path("result"))
 }
 
+// GetTxPayload 获取交易内容, 链上未找到时查找内存池, 均未找到返回 nil
+func (client *RPCClient) GetTxPayload(hash string) (*TxPayload, error) {
+	request := common.NewRPCRequest("2.0", methodGetTransaction, hash)
+	jsonParsed, err := common.SendRPCRequst(client.RPCHost, request)
+	if err != nil {
+		return nil, fmt.Errorf("GetTxPayload SendRPCRequst error --- %s", err)
+	}
+
+	if jsonParsed.Path("error").Data() != nil {
+		msg, _ := jsonParsed.Path("error").Data().(string)
+		if !strings.Contains(msg, "not found") {
+			return nil, fmt.Errorf("GetTxPayload rpc error --- %s", msg)
+		}
+	} else if _, ok := jsonParsed.Path("error.code").Data().(float64); ok /*&& value > 0*/ {
+		msg, _ := jsonParsed.Path("error.message").Data().(string)
+		if !strings.Contains(msg, "not found") {
+			return nil, fmt.Errorf("GetTxPayload rpc error --- %s", msg)
+		}
+	} else if jsonParsed.Path("result").Data() != nil {
+		return decodeTxPayload(jsonParsed.Path("result"))
+	}
+
+	request = common.NewRPCRequest("2.0", methodTxPool)
+	jsonParsed, err = common.SendRPCRequst(client.RPCHost, request)
+	if err != nil {
+		return nil, fmt.Errorf("GetTxPayload SendRPCRequst error --- %s", err)
+	}
+
+	if jsonParsed.Path("error").Data() != nil {
+		msg, _ := jsonParsed.Path("error").Data().(string)
+		return nil, fmt.Errorf("GetTxPayload error --- %s", msg)
+	}
+
+	if /*value*/ _, ok := jsonParsed.Path("error.code").Data().(float64); ok /*&& value > 0*/ {
+		msg, _ := jsonParsed.Path("error.message").Data().(string)
+		return nil, fmt.Errorf("GetTxPayload error --- %s", msg)
+	}
+
+	for _, section := range []string{"pending", "queued"} {
+		children, _ := jsonParsed.S("result", section).ChildrenMap()
+		for _, child := range children {
+			tchildren, _ := child.ChildrenMap()
+			for _, tchild := range tchildren {
+				if txHash, _ := tchild.Path("hash").Data().(string); strings.EqualFold(txHash, hash) {
+					return decodeTxPayload(tchild)
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func decodeTxPayload(jsonParsed *gabs.Container) (*TxPayload, error) {
+	bigValue := func(path string) *big.Int {
+		ret := new(big.Int)
+		if str, ok := jsonParsed.Path(path).Data().(string); ok {
+			ret.UnmarshalJSON([]byte(str))
+		}
+		return ret
+	}
+
+	payload := &TxPayload{
+		Value:    bigValue("value"),
+		Nonce:    bigValue("nonce").Uint64(),
+		Gas:      bigValue("gas").Uint64(),
+		GasPrice: bigValue("gasPrice"),
+		Height:   bigValue("blockHeight").Int64(),
+	}
+	payload.Hash, _ = jsonParsed.Path("hash").Data().(string)
+	from, _ := jsonParsed.Path("from").Data().(string)
+	payload.From = strings.ToLower(from)
+	if cnt, _ := jsonParsed.ArrayCount("tos"); cnt > 1 {
+		return nil, fmt.Errorf("transaction %s has %d recipients", payload.Hash, cnt)
+	} else if cnt == 1 {
+		to, _ := jsonParsed.S("tos").Index(0).Data().(string)
+		payload.To = strings.ToLower(to)
+	}
+	if input, ok := jsonParsed.Path("input").Data().(string); ok {
+		data, err := hex.DecodeString(strings.TrimPrefix(input, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s invalid input --- %s", payload.Hash, err)
+		}
+		payload.Data = data
+	}
+	return payload, nil
+}
+
+// GetTransactionStatus 获取已打包交易的执行结果
+func (client *RPCClient) GetTransactionStatus(hash string) (bool, error) {
+	request := common.NewRPCRequest("2.0", methodGetTransactionReceipt, hash)
+	jsonParsed, err := common.SendRPCRequst(client.RPCHost, request)
+	if err != nil {
+		return false, fmt.Errorf("GetTransactionStatus SendRPCRequst error --- %s", err)
+	}
+
+	if jsonParsed.Path("error").Data() != nil {
+		msg, _ := jsonParsed.Path("error").Data().(string)
+		return false, fmt.Errorf("GetTransactionStatus rpc error --- %s", msg)
+	}
+
+	if _, ok := jsonParsed.Path("error.code").Data().(float64); ok /*&& value > 0*/ {
+		msg, _ := jsonParsed.Path("error.message").Data().(string)
+		return false, fmt.Errorf("GetTransactionStatus rpc error --- %s", msg)
+	}
+
+	r, ok := jsonParsed.Path("result.status").Data().(string)
+	if !ok {
+		return true, nil
+	}
+	ret := new(big.Int)
+	ret.UnmarshalJSON([]byte(r))
+	return ret.Sign() > 0, nil
+}
+
 // GetGasPrice 获取费率
 func (client *RPCClient) GetGasPrice() (*big.Int, error) {
 	request := common.NewRPCRequest("2.0", methodGasPrice)
@@ -203,6 +357,81 @@ func (client *RPCClient) GetGasPrice() (*big.Int, error) {
 	return ret, nil
 }
 
+// Call 只读调用合约
+func (client *RPCClient) Call(from string, to string, data []byte) ([]byte, error) {
+	params := map[string]interface{}{
+		"To":          to,
+		"Data":        fmt.Sprintf("0x%x", data),
+		"BlockHeight": "latest",
+	}
+	if len(from) > 0 {
+		params["From"] = from
+	}
+	request := common.NewRPCRequest("2.0", methodCall, params)
+	jsonParsed, err := common.SendRPCRequst(client.RPCHost, request)
+	if err != nil {
+		return nil, fmt.Errorf("Call SendRPCRequst error --- %s", err)
+	}
+
+	if jsonParsed.Path("error").Data() != nil {
+		msg, _ := jsonParsed.Path("error").Data().(string)
+		return nil, fmt.Errorf("Call rpc error --- %s", msg)
+	}
+
+	if /*value*/ _, ok := jsonParsed.Path("error.code").Data().(float64); ok /*&& value > 0*/ {
+		msg, _ := jsonParsed.Path("error.message").Data().(string)
+		return nil, fmt.Errorf("Call rpc error --- %s", msg)
+	}
+
+	if jsonParsed.Path("result").Data() == nil {
+		return nil, nil
+	}
+
+	r, ok := jsonParsed.Path("result").Data().(string)
+	if !ok {
+		return nil, fmt.Errorf("Call Path('result') interface error --- %s", jsonParsed.String())
+	}
+	return hex.DecodeString(strings.TrimPrefix(r, "0x"))
+}
+
+// EstimateGas 模拟执行交易, 估算燃料大小
+func (client *RPCClient) EstimateGas(from string, to string, value *big.Int, data []byte) (*big.Int, error) {
+	params := map[string]interface{}{
+		"From": from,
+		"Data": fmt.Sprintf("0x%x", data),
+	}
+	if len(to) > 0 {
+		params["To"] = to
+	}
+	if value != nil {
+		params["Value"] = fmt.Sprintf("0x%x", value)
+	}
+	request := common.NewRPCRequest("2.0", methodEstimateGas, params)
+	jsonParsed, err := common.SendRPCRequst(client.RPCHost, request)
+	if err != nil {
+		return nil, fmt.Errorf("EstimateGas SendRPCRequst error --- %s", err)
+	}
+
+	if jsonParsed.Path("error").Data() != nil {
+		msg, _ := jsonParsed.Path("error").Data().(string)
+		return nil, fmt.Errorf("EstimateGas rpc error --- %s", msg)
+	}
+
+	if /*value*/ _, ok := jsonParsed.Path("error.code").Data().(float64); ok /*&& value > 0*/ {
+		msg, _ := jsonParsed.Path("error.message").Data().(string)
+		return nil, fmt.Errorf("EstimateGas rpc error --- %s", msg)
+	}
+
+	r, ok := jsonParsed.Path("result").Data().(string)
+	if !ok {
+		return nil, fmt.Errorf("EstimateGas Path('result') interface error --- %v", reflect.TypeOf(jsonParsed.Path("result").Data()))
+	}
+
+	ret := new(big.Int)
+	ret.UnmarshalJSON([]byte(r))
+	return ret, nil
+}
+
 // SendRawTransaction 发送交易
 func (client *RPCClient) SendRawTransaction(signed string) (string, error) {
 	request := common.NewRPCRequest("2.0", methodSendRawTransaction, signed)
@@ -396,6 +625,7 @@ func (client *RPCClient) decodeTransactionJSON(jsonParsed *gabs.Container) (*Tra
 	}
 
 	tx.Fee = new(big.Int).Mul(gasUsed, gasprice)
+	tx.GasPrice = gasprice
 	tx.Size = gasUsed.Int64()
 	tx.Ins = append(tx.Ins, &InOut{
 		Addresses: []string{from},
@@ -0,0 +1,219 @@
+// 秘密分享: 将用户商或服务主密钥拆分为 N 个分片文件, 任意 K 个分片恢复, 用于灾难恢复
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/erick785/services/common/log"
+	"github.com/erick785/services/common/wallet"
+	"github.com/erick785/services/common/wallet/shamir"
+)
+
+// 分片名称: 主密钥为 masterkey, 用户商为 wallet:用户名
+const (
+	labelMasterKey = "masterkey"
+	labelWallet    = "wallet:"
+)
+
+func main() {
+	// 模式: 拆分或恢复, 恢复时分片文件为其余参数
+	split := flag.Bool("split", false, "split -wallet entropy or -masterkey into share files under -out")
+	combine := flag.Bool("combine", false, "combine share files given as arguments")
+	// 拆分对象: 用户商或主密钥(masterkeyfile 或环境变量 WALLET_MASTER_KEYS 的内容)
+	name := flag.String("wallet", "", "wallet (user) name")
+	masterkey := flag.Bool("masterkey", false, "split the wallet master key instead of a wallet")
+	total := flag.Int("total", 5, "number of shares")
+	threshold := flag.Int("threshold", 3, "shares required to recover")
+	out := flag.String("out", "", "split: output directory; combine: output file, empty print")
+	// 恢复后写回用户表
+	restore := flag.Bool("restore", false, "combine: import the recovered entropy into the wallet db")
+	overwrite := flag.Bool("overwrite", false, "restore: replace the entropy of an existing wallet")
+	// DB
+	wdbname := flag.String("wdbname", "wallet", "db name")
+	dbhost := flag.String("dbhost", "127.0.0.1:3306", "db host, ip:port")
+	dbuser := flag.String("dbuser", "root", "db user")
+	dbpassword := flag.String("dbpassword", "root", "db password")
+	// 钱包主密钥: 密钥文件优先, 否则读取环境变量 WALLET_MASTER_KEYS
+	masterkeyfile := flag.String("masterkeyfile", "", "wallet master key file, lines of version:hexkey, highest version encrypts")
+	insecurelegacy := flag.Bool("insecurelegacy", false, "allow running without a master key, wallets stay in the legacy user-keyed format")
+
+	flag.Parse()
+
+	openDB := func() *wallet.Mysql {
+		keys, err := wallet.LoadKeyRing(*masterkeyfile, "WALLET_MASTER_KEYS")
+		if err != nil {
+			panic(err)
+		}
+		if keys == nil && !*insecurelegacy {
+			panic(wallet.ErrNoMasterKey)
+		}
+		wltdb := &wallet.Mysql{
+			DBName: strings.ToLower(*wdbname),
+			DBHost: *dbhost,
+			DBUser: *dbuser,
+			DBPWD:  *dbpassword,
+			Keys:   keys,
+		}
+		if err := wltdb.Open(); err != nil {
+			panic(err)
+		}
+		return wltdb
+	}
+
+	var err error
+	if *split && *masterkey {
+		err = splitMasterKey(*masterkeyfile, *out, *total, *threshold)
+	} else if *split {
+		wltdb := openDB()
+		defer wltdb.Close()
+		err = splitWallet(wltdb, *name, *out, *total, *threshold)
+	} else if *combine {
+		var label string
+		var secret []byte
+		if label, secret, err = combineFiles(flag.Args()); err == nil {
+			if !*restore {
+				err = writeSecret(label, secret, *out)
+			} else if !strings.HasPrefix(label, labelWallet) {
+				err = fmt.Errorf("shares of %s can not be restored into the wallet db", label)
+			} else {
+				wltdb := openDB()
+				defer wltdb.Close()
+				err = restoreWallet(wltdb, strings.TrimPrefix(label, labelWallet), secret, *overwrite)
+			}
+			zero(secret)
+		}
+	} else {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Errorf("[Shamir] %v", err)
+		os.Exit(1)
+	}
+}
+
+// splitMasterKey 拆分主密钥文件(或环境变量)的内容, 恢复后可直接作为 masterkeyfile
+func splitMasterKey(file string, out string, total, threshold int) error {
+	var secret []byte
+	if len(file) > 0 {
+		bts, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		secret = bts
+	} else {
+		secret = []byte(os.Getenv("WALLET_MASTER_KEYS"))
+	}
+	defer zero(secret)
+	if _, err := wallet.ParseKeyRing(string(secret)); err != nil {
+		return err
+	}
+	return writeShares(labelMasterKey, secret, out, total, threshold)
+}
+
+// splitWallet 拆分用户商; BIP39 口令不保存在服务端, 不包含在分片中
+func splitWallet(wltdb *wallet.Mysql, name string, out string, total, threshold int) error {
+	wlt, err := wltdb.GetWallet(name)
+	if err != nil {
+		return err
+	}
+	if wlt == nil {
+		return fmt.Errorf("wallet %s not found", name)
+	}
+	if wlt.WatchOnly() {
+		return wallet.ErrWatchOnly
+	}
+	defer wlt.Zero()
+	entropy := wlt.Entropy()
+	defer zero(entropy)
+	return writeShares(labelWallet+name, entropy, out, total, threshold)
+}
+
+// writeShares 拆分并将每个分片写入单独的文件 名称-序号-of-总数.json
+func writeShares(label string, secret []byte, out string, total, threshold int) error {
+	if len(out) == 0 {
+		return errors.New("output directory required")
+	}
+	shares, err := shamir.Split(label, secret, total, threshold)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(out, 0700); err != nil {
+		return err
+	}
+	prefix := strings.Replace(label, ":", "-", -1)
+	for _, share := range shares {
+		bts, err := share.Marshal()
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(out, fmt.Sprintf("%s-%d-of-%d.json", filepath.Base(prefix), share.Index, share.Total))
+		if err := ioutil.WriteFile(file, bts, 0600); err != nil {
+			return err
+		}
+		log.Infof("[Shamir] %s share %d/%d (threshold %d) written to %s", label, share.Index, share.Total, share.Threshold, file)
+	}
+	return nil
+}
+
+// combineFiles 读取分片文件并恢复秘密
+func combineFiles(files []string) (string, []byte, error) {
+	shares := []*shamir.Share{}
+	for _, file := range files {
+		bts, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", nil, err
+		}
+		share, err := shamir.ParseShare(bts)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s: %v", file, err)
+		}
+		shares = append(shares, share)
+	}
+	secret, err := shamir.Combine(shares)
+	if err != nil {
+		return "", nil, err
+	}
+	return shares[0].Label, secret, nil
+}
+
+// restoreWallet 将恢复的用户商写回用户表, 已分配的地址序号及口令地址保留;
+// 不能查询链上余额, 已有钱包的商与恢复结果不同时不替换, 需通过 importwallet 替换
+func restoreWallet(wltdb *wallet.Mysql, name string, secret []byte, overwrite bool) error {
+	if _, err := wltdb.ImportWallet(name, hex.EncodeToString(secret), overwrite, nil); err != nil {
+		return err
+	}
+	log.Infof("[Shamir] wallet %s restored", name)
+	return nil
+}
+
+// writeSecret 输出恢复的秘密: 用户商为十六进制, 主密钥为密钥文件内容
+func writeSecret(label string, secret []byte, out string) error {
+	content := secret
+	if strings.HasPrefix(label, labelWallet) {
+		content = []byte(hex.EncodeToString(secret) + "\n")
+		defer zero(content)
+	}
+	if len(out) == 0 {
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+	if err := ioutil.WriteFile(out, content, 0600); err != nil {
+		return err
+	}
+	log.Infof("[Shamir] %s recovered to %s", label, out)
+	return nil
+}
+
+func zero(bts []byte) {
+	for i := range bts {
+		bts[i] = 0
+	}
+}
@@ -0,0 +1,80 @@
+// 签名服务: 持有钱包主密钥, 为 API 服务(-signer 参数)创建、导入、导出钱包及签名交易, 商及私钥不进入 API 进程
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/erick785/services/common/log"
+	"github.com/erick785/services/common/signer"
+	"github.com/erick785/services/common/wallet"
+)
+
+func main() {
+	// listen 地址, 默认只监听本机
+	listen := flag.String("listen", "127.0.0.1:8090", "signer listen address, ip:port")
+	// Log 级别
+	level := flag.String("level", "info", "log level, debug | info | warn | error | fatal | panic")
+	// DB
+	wdbname := flag.String("wdbname", "wallet", "db name")
+	dbhost := flag.String("dbhost", "127.0.0.1:3306", "db host, ip:port")
+	dbuser := flag.String("dbuser", "root", "db user")
+	dbpassword := flag.String("dbpassword", "root", "db password")
+
+	// 钱包主密钥: 密钥文件优先, 否则读取环境变量 WALLET_MASTER_KEYS
+	masterkeyfile := flag.String("masterkeyfile", "", "wallet master key file, lines of version:hexkey, highest version encrypts")
+	rotatekeys := flag.Bool("rotatekeys", false, "re-encrypt all wallets under the current master key and exit")
+	insecurelegacy := flag.Bool("insecurelegacy", false, "allow running without a master key, wallets stay in the legacy user-keyed format")
+
+	// 访问令牌: 未指定时读取环境变量 SIGNER_TOKEN
+	token := flag.String("token", "", "signer access token, default env SIGNER_TOKEN")
+	insecurenotoken := flag.Bool("insecurenotoken", false, "allow running without an access token, any local process can sign")
+
+	flag.Parse()
+	log.SetLevel(strings.ToLower(*level))
+
+	if len(*token) == 0 {
+		*token = os.Getenv("SIGNER_TOKEN")
+	}
+	if len(*token) == 0 && !*insecurenotoken {
+		panic("signer access token not configured, set -token or SIGNER_TOKEN")
+	}
+	if len(*token) == 0 {
+		log.Warnf("[Signer] running without access token")
+	}
+
+	keys, err := wallet.LoadKeyRing(*masterkeyfile, "WALLET_MASTER_KEYS")
+	if err != nil {
+		panic(err)
+	}
+	if keys == nil && !*insecurelegacy {
+		panic(wallet.ErrNoMasterKey)
+	}
+	wltdb := &wallet.Mysql{
+		DBName: strings.ToLower(*wdbname),
+		DBHost: *dbhost,
+		DBUser: *dbuser,
+		DBPWD:  *dbpassword,
+		Keys:   keys,
+	}
+	if err := wltdb.Open(); err != nil {
+		panic(err)
+	}
+	defer wltdb.Close()
+	if *rotatekeys {
+		rotated, err := wltdb.RotateKeys()
+		if err != nil {
+			log.Errorf("[Signer] RotateKeys rotated %d, error:%v", rotated, err)
+			return
+		}
+		log.Infof("[Signer] RotateKeys rotated %d wallets to master key %d", rotated, keys.Current())
+		return
+	}
+
+	log.Infof("[Signer] listening on %s", *listen)
+	if err := http.ListenAndServe(*listen, signer.NewHandler(signer.NewLocalSigner(wltdb), *token)); err != nil {
+		log.Errorf("[Signer] ListenAndServe err %v", err)
+	}
+}
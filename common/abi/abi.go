@@ -0,0 +1,241 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/erick785/uranus/common/crypto"
+)
+
+var (
+	tt256   = new(big.Int).Lsh(big.NewInt(1), 256)
+	tt255   = new(big.Int).Lsh(big.NewInt(1), 255)
+	wordLen = 32
+)
+
+// ParseSignature 解析方法签名, 如 transfer(address,uint256) -> transfer, [address uint256]
+func ParseSignature(sig string) (string, []string, error) {
+	sig = strings.Replace(sig, " ", "", -1)
+	start := strings.Index(sig, "(")
+	if start < 0 || !strings.HasSuffix(sig, ")") {
+		return "", nil, fmt.Errorf("invalid method signature: %s", sig)
+	}
+	name := sig[:start]
+	inner := sig[start+1 : len(sig)-1]
+	if len(inner) == 0 {
+		return name, nil, nil
+	}
+	return name, strings.Split(inner, ","), nil
+}
+
+// MethodID 方法选择器, keccak256(签名) 前 4 字节
+func MethodID(sig string) ([]byte, error) {
+	name, types, err := ParseSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256([]byte(fmt.Sprintf("%s(%s)", name, strings.Join(types, ","))))[:4], nil
+}
+
+// PackMethod 编码方法调用数据: 方法选择器 + 参数
+func PackMethod(sig string, args []string) ([]byte, error) {
+	id, err := MethodID(sig)
+	if err != nil {
+		return nil, err
+	}
+	_, types, _ := ParseSignature(sig)
+	data, err := Pack(types, args)
+	if err != nil {
+		return nil, err
+	}
+	return append(id, data...), nil
+}
+
+// Pack 按类型编码参数, 参数均以字符串表示
+// 支持 address bool uintN intN bytesN string bytes
+func Pack(types []string, args []string) ([]byte, error) {
+	if len(types) != len(args) {
+		return nil, fmt.Errorf("argument count mismatch: %d for %d", len(args), len(types))
+	}
+	var head, tail []byte
+	for i, typ := range types {
+		if isDynamic(typ) {
+			head = append(head, packNum(big.NewInt(int64(len(types)*wordLen+len(tail))))...)
+			word, err := packDynamic(typ, args[i])
+			if err != nil {
+				return nil, err
+			}
+			tail = append(tail, word...)
+			continue
+		}
+		word, err := packStatic(typ, args[i])
+		if err != nil {
+			return nil, err
+		}
+		head = append(head, word...)
+	}
+	return append(head, tail...), nil
+}
+
+// Unpack 按类型解码返回数据
+// address bytesN bytes 返回十六进制字符串, uintN intN 返回 *big.Int
+func Unpack(types []string, data []byte) ([]interface{}, error) {
+	if len(data) < len(types)*wordLen {
+		return nil, fmt.Errorf("data too short: %d for %d values", len(data), len(types))
+	}
+	values := []interface{}{}
+	for i, typ := range types {
+		word := data[i*wordLen : (i+1)*wordLen]
+		if !isDynamic(typ) {
+			value, err := unpackStatic(typ, word)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			continue
+		}
+		offset := new(big.Int).SetBytes(word)
+		if !offset.IsInt64() || offset.Int64()+int64(wordLen) > int64(len(data)) {
+			return nil, fmt.Errorf("invalid offset for %s", typ)
+		}
+		size := new(big.Int).SetBytes(data[offset.Int64() : offset.Int64()+int64(wordLen)])
+		start := offset.Int64() + int64(wordLen)
+		if !size.IsInt64() || start+size.Int64() > int64(len(data)) {
+			return nil, fmt.Errorf("invalid length for %s", typ)
+		}
+		bts := data[start : start+size.Int64()]
+		if typ == "string" {
+			values = append(values, string(bts))
+		} else {
+			values = append(values, "0x"+hex.EncodeToString(bts))
+		}
+	}
+	return values, nil
+}
+
+func isDynamic(typ string) bool {
+	return typ == "string" || typ == "bytes"
+}
+
+func typeSize(typ string, prefix string, max int) (int, error) {
+	str := strings.TrimPrefix(typ, prefix)
+	if len(str) == 0 {
+		return max, nil
+	}
+	size, err := strconv.Atoi(str)
+	if err != nil || size <= 0 || size > max {
+		return 0, fmt.Errorf("unsupported type: %s", typ)
+	}
+	return size, nil
+}
+
+func packNum(value *big.Int) []byte {
+	word := make([]byte, wordLen)
+	bts := new(big.Int).Mod(value, tt256).Bytes()
+	copy(word[wordLen-len(bts):], bts)
+	return word
+}
+
+func decodeHex(str string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(str, "0x"), "0X"))
+}
+
+func packStatic(typ string, arg string) ([]byte, error) {
+	switch {
+	case typ == "address":
+		bts, err := decodeHex(arg)
+		if err != nil || len(bts) != 20 {
+			return nil, fmt.Errorf("invalid address: %s", arg)
+		}
+		word := make([]byte, wordLen)
+		copy(word[wordLen-len(bts):], bts)
+		return word, nil
+	case typ == "bool":
+		value, err := strconv.ParseBool(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool: %s", arg)
+		}
+		if value {
+			return packNum(big.NewInt(1)), nil
+		}
+		return packNum(big.NewInt(0)), nil
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		unsigned := strings.HasPrefix(typ, "uint")
+		prefix := "int"
+		if unsigned {
+			prefix = "uint"
+		}
+		size, err := typeSize(typ, prefix, 256)
+		if err != nil || size%8 != 0 {
+			return nil, fmt.Errorf("unsupported type: %s", typ)
+		}
+		value, ok := new(big.Int).SetString(arg, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid number: %s", arg)
+		}
+		if unsigned && (value.Sign() < 0 || value.BitLen() > size) {
+			return nil, fmt.Errorf("%s out of range for %s", arg, typ)
+		}
+		if !unsigned {
+			max := new(big.Int).Lsh(big.NewInt(1), uint(size-1))
+			if value.Cmp(new(big.Int).Neg(max)) < 0 || value.Cmp(max) >= 0 {
+				return nil, fmt.Errorf("%s out of range for %s", arg, typ)
+			}
+		}
+		return packNum(value), nil
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := typeSize(typ, "bytes", 32)
+		if err != nil {
+			return nil, err
+		}
+		bts, err := decodeHex(arg)
+		if err != nil || len(bts) > size {
+			return nil, fmt.Errorf("invalid %s: %s", typ, arg)
+		}
+		word := make([]byte, wordLen)
+		copy(word, bts)
+		return word, nil
+	}
+	return nil, fmt.Errorf("unsupported type: %s", typ)
+}
+
+func packDynamic(typ string, arg string) ([]byte, error) {
+	bts := []byte(arg)
+	if typ == "bytes" {
+		var err error
+		if bts, err = decodeHex(arg); err != nil {
+			return nil, fmt.Errorf("invalid bytes: %s", arg)
+		}
+	}
+	word := packNum(big.NewInt(int64(len(bts))))
+	padded := make([]byte, (len(bts)+wordLen-1)/wordLen*wordLen)
+	copy(padded, bts)
+	return append(word, padded...), nil
+}
+
+func unpackStatic(typ string, word []byte) (interface{}, error) {
+	switch {
+	case typ == "address":
+		return "0x" + hex.EncodeToString(word[wordLen-20:]), nil
+	case typ == "bool":
+		return new(big.Int).SetBytes(word).Sign() != 0, nil
+	case strings.HasPrefix(typ, "uint"):
+		return new(big.Int).SetBytes(word), nil
+	case strings.HasPrefix(typ, "int"):
+		value := new(big.Int).SetBytes(word)
+		if value.Cmp(tt255) >= 0 {
+			value.Sub(value, tt256)
+		}
+		return value, nil
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := typeSize(typ, "bytes", 32)
+		if err != nil {
+			return nil, err
+		}
+		return "0x" + hex.EncodeToString(word[:size]), nil
+	}
+	return nil, fmt.Errorf("unsupported type: %s", typ)
+}
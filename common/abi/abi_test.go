@@ -0,0 +1,133 @@
+package abi
+
+import (
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestParseSignature(t *testing.T) {
+	name, types, err := ParseSignature("transfer(address, uint256)")
+	if err != nil || name != "transfer" || !reflect.DeepEqual(types, []string{"address", "uint256"}) {
+		t.Errorf("parse mismatch: have %v %v (%v)", name, types, err)
+	}
+	if _, types, err := ParseSignature("(uint256)"); err != nil || !reflect.DeepEqual(types, []string{"uint256"}) {
+		t.Errorf("parse constructor mismatch: have %v (%v)", types, err)
+	}
+	if _, _, err := ParseSignature("transfer"); err == nil {
+		t.Errorf("expected error for signature without arguments")
+	}
+}
+
+func TestMethodID(t *testing.T) {
+	tests := []struct {
+		sig string
+		id  string
+	}{
+		{"transfer(address,uint256)", "a9059cbb"},
+		{"transfer(address, uint256)", "a9059cbb"},
+		{"balanceOf(address)", "70a08231"},
+		{"approve(address,uint256)", "095ea7b3"},
+		{"totalSupply()", "18160ddd"},
+		{"baz(uint32,bool)", "cdcd77c0"},
+	}
+	for i, tt := range tests {
+		id, err := MethodID(tt.sig)
+		if err != nil {
+			t.Errorf("test %d: method id error: %v", i, err)
+		} else if hex.EncodeToString(id) != tt.id {
+			t.Errorf("test %d: method id mismatch: have %x, want %s", i, id, tt.id)
+		}
+	}
+}
+
+func TestPackMethod(t *testing.T) {
+	tests := []struct {
+		sig    string
+		args   []string
+		output string
+	}{
+		//token 转账
+		{"transfer(address,uint256)", []string{"0x83f1caadabeec2945b73087f803d404f054cc2b7", "1000"},
+			"a9059cbb" +
+				"00000000000000000000000083f1caadabeec2945b73087f803d404f054cc2b7" +
+				"00000000000000000000000000000000000000000000000000000000000003e8"},
+		//Solidity ABI 规范示例 baz(69, true)
+		{"baz(uint32,bool)", []string{"69", "true"},
+			"cdcd77c0" +
+				"0000000000000000000000000000000000000000000000000000000000000045" +
+				"0000000000000000000000000000000000000000000000000000000000000001"},
+	}
+	for i, tt := range tests {
+		data, err := PackMethod(tt.sig, tt.args)
+		if err != nil {
+			t.Errorf("test %d: pack method error: %v", i, err)
+		} else if hex.EncodeToString(data) != tt.output {
+			t.Errorf("test %d: pack method mismatch: have %x, want %s", i, data, tt.output)
+		}
+	}
+}
+
+func TestPack(t *testing.T) {
+	tests := []struct {
+		types  []string
+		args   []string
+		output string
+	}{
+		{[]string{"uint256"}, []string{"1"}, "0000000000000000000000000000000000000000000000000000000000000001"},
+		{[]string{"int8"}, []string{"-1"}, "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"},
+		{[]string{"bool"}, []string{"true"}, "0000000000000000000000000000000000000000000000000000000000000001"},
+		{[]string{"address"}, []string{"0x83f1caadabeec2945b73087f803d404f054cc2b7"}, "00000000000000000000000083f1caadabeec2945b73087f803d404f054cc2b7"},
+		{[]string{"bytes2"}, []string{"0xabcd"}, "abcd000000000000000000000000000000000000000000000000000000000000"},
+		{[]string{"uint256", "string"}, []string{"0x10", "abc"},
+			"0000000000000000000000000000000000000000000000000000000000000010" +
+				"0000000000000000000000000000000000000000000000000000000000000040" +
+				"0000000000000000000000000000000000000000000000000000000000000003" +
+				"6162630000000000000000000000000000000000000000000000000000000000"},
+	}
+	for i, tt := range tests {
+		data, err := Pack(tt.types, tt.args)
+		if err != nil {
+			t.Errorf("test %d: pack error: %v", i, err)
+		} else if hex.EncodeToString(data) != tt.output {
+			t.Errorf("test %d: pack mismatch: have %x, want %s", i, data, tt.output)
+		}
+	}
+
+	invalids := []struct {
+		types []string
+		args  []string
+	}{
+		{[]string{"uint8"}, []string{"256"}},
+		{[]string{"uint256"}, []string{"-1"}},
+		{[]string{"int8"}, []string{"128"}},
+		{[]string{"address"}, []string{"0x1234"}},
+		{[]string{"uint256[]"}, []string{"1"}},
+		{[]string{"uint256"}, []string{}},
+	}
+	for i, tt := range invalids {
+		if _, err := Pack(tt.types, tt.args); err == nil {
+			t.Errorf("invalid %d: expected error for %v %v", i, tt.types, tt.args)
+		}
+	}
+}
+
+func TestUnpack(t *testing.T) {
+	types := []string{"uint256", "string", "int8", "bool"}
+	data, err := Pack(types, []string{"1000", "hello", "-2", "true"})
+	if err != nil {
+		t.Fatalf("pack error: %v", err)
+	}
+	values, err := Unpack(types, data)
+	if err != nil {
+		t.Fatalf("unpack error: %v", err)
+	}
+	want := []interface{}{big.NewInt(1000), "hello", big.NewInt(-2), true}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("unpack mismatch: have %v, want %v", values, want)
+	}
+	if _, err := Unpack(types, data[:64]); err == nil {
+		t.Errorf("expected error for short data")
+	}
+}
@@ -37,18 +37,24 @@ func (api *APIRespone) MD5() string {
 
 //HistoryInfo 历史交易信息
 type HistoryInfo struct {
-	Hash          string   `json:"hash"`          // 交易哈希
-	From          string   `json:"from"`          // 发起者
-	To            string   `json:"to"`            // 接受者（合约地址）
-	Value         *big.Int `json:"value"`         // 金额
-	TValue        *big.Int `json:"tvalue"`        // 金额实际变动
-	Fee           *big.Int `json:"fee"`           // 手续费
-	Size          int64    `json:"size"`          // gas used
-	Time          int64    `json:"time"`          // 交易时间
-	Height        int64    `json:"height"`        // 区块号
-	Confirmations int64    `json:"confirmations"` // 确认数
-	Signature     string   `json:"signature"`     // 签名
-	Status        int      `json:"status"`        // 状态码
+	Hash          string   `json:"hash"`                // 交易哈希
+	From          string   `json:"from"`                // 发起者
+	To            string   `json:"to"`                  // 接受者（合约地址）
+	Value         *big.Int `json:"value"`               // 金额
+	TValue        *big.Int `json:"tvalue"`              // 金额实际变动
+	Fee           *big.Int `json:"fee"`                 // 手续费
+	Size          int64    `json:"size"`                // gas used
+	Time          int64    `json:"time"`                // 交易时间
+	Height        int64    `json:"height"`              // 区块号
+	Confirmations int64    `json:"confirmations"`       // 确认数
+	Signature     string   `json:"signature"`           // 签名
+	Status        int      `json:"status"`              // 状态码
+	OrderID       string   `json:"order_id,omitempty"`  // 订单号
+	TxStatus      string   `json:"tx_status,omitempty"` // 订单交易状态
+
+	ValueDecimal  string `json:"value_decimal,omitempty"`  // 金额(十进制, 按币种精度)
+	TValueDecimal string `json:"tvalue_decimal,omitempty"` // 金额实际变动(十进制, 按币种精度)
+	FeeDecimal    string `json:"fee_decimal,omitempty"`    // 手续费(十进制, 按原生币精度)
 }
 
 // BlockInfo 区块信息
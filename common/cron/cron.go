@@ -0,0 +1,162 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field 字段取值范围
+type field struct {
+	name     string
+	min, max uint
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Schedule 五段式 cron 表达式: 分 时 日 月 周
+// 每段支持 * 数字 a-b 列表 a,b 步长 */n a-b/n, 周日为 0
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	domStar, dowStar bool
+}
+
+// Parse 解析 cron 表达式
+func Parse(spec string) (*Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("expected %d fields, found %d: %s", len(fields), len(parts), spec)
+	}
+	bits := make([]uint64, len(fields))
+	for i, part := range parts {
+		b, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+	return &Schedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: parts[2] == "*",
+		dowStar: parts[4] == "*",
+	}, nil
+}
+
+func parseField(str string, f field) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.Split(str, ",") {
+		b, err := parseRange(expr, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+func parseRange(expr string, f field) (uint64, error) {
+	rangeAndStep := strings.Split(expr, "/")
+	lowAndHigh := strings.Split(rangeAndStep[0], "-")
+	start, end, step := f.min, f.max, uint(1)
+
+	if lowAndHigh[0] != "*" {
+		var err error
+		if start, err = parseUint(lowAndHigh[0], f); err != nil {
+			return 0, err
+		}
+		end = start
+		if len(lowAndHigh) == 2 {
+			if end, err = parseUint(lowAndHigh[1], f); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if len(lowAndHigh) > 2 || (lowAndHigh[0] == "*" && len(lowAndHigh) > 1) {
+		return 0, fmt.Errorf("invalid %s range: %s", f.name, expr)
+	}
+
+	switch len(rangeAndStep) {
+	case 1:
+	case 2:
+		s, err := strconv.ParseUint(rangeAndStep[1], 10, 32)
+		if err != nil || s == 0 {
+			return 0, fmt.Errorf("invalid %s step: %s", f.name, expr)
+		}
+		step = uint(s)
+		//a/n 表示从 a 开始到最大值
+		if lowAndHigh[0] != "*" && len(lowAndHigh) == 1 {
+			end = f.max
+		}
+	default:
+		return 0, fmt.Errorf("invalid %s step: %s", f.name, expr)
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("invalid %s range: %s", f.name, expr)
+	}
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits, nil
+}
+
+func parseUint(str string, f field) (uint, error) {
+	v, err := strconv.ParseUint(str, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %s", f.name, str)
+	}
+	if uint(v) < f.min || uint(v) > f.max {
+		return 0, fmt.Errorf("%s %d out of range [%d, %d]", f.name, v, f.min, f.max)
+	}
+	return uint(v), nil
+}
+
+// Next 返回 t 之后的下一次执行时间(精确到分钟), 5 年内没有匹配时返回零值
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches 日和周都有限制时满足其一即可, 与 cron 一致
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) > 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) > 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,50 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, spec := range []string{"* * * * *", "0 9 * * 1-5", "*/15 0,12 1 */2 *", "30 8 1-7/2 * 0"} {
+		if _, err := Parse(spec); err != nil {
+			t.Errorf("Parse(%s) error %s", spec, err)
+		}
+	}
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 7", "5-1 * * * *", "*/0 * * * *", "a * * * *", "*-1 * * * *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%s) expected error", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	layout := "2006-01-02 15:04"
+	tests := []struct {
+		spec, from, next string
+	}{
+		{"* * * * *", "2019-03-01 10:00", "2019-03-01 10:01"},
+		{"0 9 * * *", "2019-03-01 09:00", "2019-03-02 09:00"},
+		{"0 9 * * 1-5", "2019-03-01 10:00", "2019-03-04 09:00"},
+		{"*/15 * * * *", "2019-03-01 10:07", "2019-03-01 10:15"},
+		{"0 0 1 * *", "2019-12-15 00:00", "2020-01-01 00:00"},
+		{"0 0 29 2 *", "2019-03-01 00:00", "2020-02-29 00:00"},
+		{"0 0 1 * 0", "2019-03-01 10:00", "2019-03-03 00:00"},
+		{"30 8 31 * *", "2019-04-01 00:00", "2019-05-31 08:30"},
+	}
+	for _, test := range tests {
+		s, err := Parse(test.spec)
+		if err != nil {
+			t.Fatalf("Parse(%s) error %s", test.spec, err)
+		}
+		from, _ := time.ParseInLocation(layout, test.from, time.UTC)
+		if next := s.Next(from).Format(layout); next != test.next {
+			t.Errorf("Next(%s, %s) = %s, want %s", test.spec, test.from, next, test.next)
+		}
+	}
+
+	s, _ := Parse("0 0 30 2 *")
+	if next := s.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Next(0 0 30 2 *) = %s, want zero", next)
+	}
+}
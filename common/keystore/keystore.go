@@ -0,0 +1,249 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/erick785/uranus/common/crypto"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// scrypt 参数, 与常用钱包一致
+const (
+	StandardScryptN = 1 << 18 // 约 256MB 内存
+	StandardScryptP = 1
+	LightScryptN    = 1 << 12 // 约 4MB 内存
+	LightScryptP    = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+	version     = 3
+
+	// 解密时接受的密钥派生参数上限, 防止导入的文件耗尽内存或 CPU
+	maxScryptP   = 16
+	maxPBKDF2C   = 1 << 20
+	maxKDFParams = 1 << 30
+)
+
+// ErrDecrypt 口令错误或文件被修改
+var ErrDecrypt = errors.New("could not decrypt key with given password")
+
+// Keystore 加密的私钥文件(Web3 Secret Storage v3)
+type Keystore struct {
+	Address string     `json:"address"` // 地址, 十六进制不含 0x
+	Crypto  CryptoJSON `json:"crypto"`
+	ID      string     `json:"id"`
+	Version int        `json:"version"`
+}
+
+// CryptoJSON 加密参数
+type CryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams CipherParams           `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+// CipherParams 加密算法参数
+type CipherParams struct {
+	IV string `json:"iv"`
+}
+
+// EncryptKey 以口令加密私钥, 返回 v3 格式的 JSON; 密钥由 scrypt 派生, 加密算法为 aes-128-ctr
+func EncryptKey(key *ecdsa.PrivateKey, password string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	derivedKey, err := scryptKey([]byte(password), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(derivedKey)
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	keyBytes := FromECDSA(key)
+	defer zero(keyBytes)
+	cipherText, err := aesCTR(derivedKey[:16], iv, keyBytes)
+	if err != nil {
+		return nil, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
+	id := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return nil, err
+	}
+	// UUID v4
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	return json.Marshal(&Keystore{
+		Address: hex.EncodeToString(crypto.PubkeyToAddress(key.PublicKey).Bytes()),
+		Crypto: CryptoJSON{
+			Cipher:     "aes-128-ctr",
+			CipherText: hex.EncodeToString(cipherText),
+			CipherParams: CipherParams{
+				IV: hex.EncodeToString(iv),
+			},
+			KDF: "scrypt",
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     scryptR,
+				"p":     scryptP,
+				"dklen": scryptDKLen,
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(mac),
+		},
+		ID:      fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		Version: version,
+	})
+}
+
+// DecryptKey 以口令解密 v3 格式的私钥文件, 支持 scrypt 及 pbkdf2(hmac-sha256) 密钥派生
+func DecryptKey(keyjson []byte, password string) (*ecdsa.PrivateKey, error) {
+	ks := &Keystore{}
+	if err := json.Unmarshal(keyjson, ks); err != nil {
+		return nil, err
+	}
+	if ks.Version != version {
+		return nil, fmt.Errorf("keystore version %d not supported", ks.Version)
+	}
+	if ks.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("cipher %s not supported", ks.Crypto.Cipher)
+	}
+	mac, err := hex.DecodeString(ks.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(ks.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(ks.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := deriveKey(&ks.Crypto, password)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(derivedKey)
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrDecrypt
+	}
+	keyBytes, err := aesCTR(derivedKey[:16], iv, cipherText)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(keyBytes)
+	return ToECDSA(keyBytes)
+}
+
+// ToECDSA 由 32 字节私钥得到 secp256k1 私钥
+func ToECDSA(keyBytes []byte) (*ecdsa.PrivateKey, error) {
+	if len(keyBytes) != 32 {
+		return nil, fmt.Errorf("invalid private key length %d", len(keyBytes))
+	}
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), keyBytes)
+	if key.D.Sign() <= 0 || key.D.Cmp(btcec.S256().N) >= 0 {
+		return nil, errors.New("invalid private key")
+	}
+	return key.ToECDSA(), nil
+}
+
+// FromECDSA 私钥的 32 字节编码
+func FromECDSA(key *ecdsa.PrivateKey) []byte {
+	return paddedBigBytes(key.D.Bytes(), 32)
+}
+
+func deriveKey(cryptoJSON *CryptoJSON, password string) ([]byte, error) {
+	params := cryptoJSON.KDFParams
+	salt, err := hex.DecodeString(paramString(params, "salt"))
+	if err != nil {
+		return nil, err
+	}
+	if dkLen := paramInt(params, "dklen"); dkLen != scryptDKLen {
+		return nil, fmt.Errorf("invalid dklen %d", dkLen)
+	}
+
+	switch cryptoJSON.KDF {
+	case "scrypt":
+		n, r, p := paramInt(params, "n"), paramInt(params, "r"), paramInt(params, "p")
+		if n <= 1 || n > StandardScryptN || r != scryptR || p <= 0 || p > maxScryptP {
+			return nil, fmt.Errorf("scrypt parameters n=%d r=%d p=%d not allowed", n, r, p)
+		}
+		return scryptKey([]byte(password), salt, n, r, p, scryptDKLen)
+	case "pbkdf2":
+		if prf := paramString(params, "prf"); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("pbkdf2 prf %s not supported", prf)
+		}
+		c := paramInt(params, "c")
+		if c <= 0 || c > maxPBKDF2C {
+			return nil, fmt.Errorf("pbkdf2 iterations %d not allowed", c)
+		}
+		return pbkdf2.Key([]byte(password), salt, c, scryptDKLen, sha256.New), nil
+	}
+	return nil, fmt.Errorf("kdf %s not supported", cryptoJSON.KDF)
+}
+
+// paramInt 整数参数, 缺失、非整数或超出范围时返回 -1
+func paramInt(params map[string]interface{}, key string) int {
+	f, ok := params[key].(float64)
+	if !ok || f != math.Trunc(f) || f < 0 || f > maxKDFParams {
+		return -1
+	}
+	return int(f)
+}
+
+func paramString(params map[string]interface{}, key string) string {
+	s, _ := params[key].(string)
+	return s
+}
+
+func aesCTR(key, iv, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid iv length %d", len(iv))
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+func paddedBigBytes(b []byte, n int) []byte {
+	if len(b) >= n {
+		return b
+	}
+	padded := make([]byte, n)
+	copy(padded[n-len(b):], b)
+	return padded
+}
+
+// zero 清零密钥材料
+func zero(bts []byte) {
+	for i := range bts {
+		bts[i] = 0
+	}
+}
@@ -0,0 +1,145 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/erick785/uranus/common/crypto"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+func TestKeystore(t *testing.T) {
+	key, err := ToECDSA(bytes.Repeat([]byte{0x11}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := EncryptKey(key, "password", LightScryptN, LightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := &Keystore{}
+	if err := json.Unmarshal(keyjson, ks); err != nil || ks.Version != 3 || ks.Crypto.KDF != "scrypt" || len(ks.Address) != 40 || len(ks.ID) != 36 {
+		t.Fatalf("keystore %s %v", keyjson, err)
+	}
+
+	decrypted, err := DecryptKey(keyjson, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(FromECDSA(decrypted), FromECDSA(key)) {
+		t.Fatalf("decrypted key %x", FromECDSA(decrypted))
+	}
+	if _, err := DecryptKey(keyjson, "wrong"); err != ErrDecrypt {
+		t.Fatalf("wrong password err %v", err)
+	}
+
+	ks.Crypto.CipherText = hex.EncodeToString(bytes.Repeat([]byte{0x22}, 32))
+	tampered, _ := json.Marshal(ks)
+	if _, err := DecryptKey(tampered, "password"); err != ErrDecrypt {
+		t.Fatalf("tampered keystore err %v", err)
+	}
+	ks.Version = 1
+	old, _ := json.Marshal(ks)
+	if _, err := DecryptKey(old, "password"); err == nil {
+		t.Fatal("version 1 expected error")
+	}
+}
+
+func TestKeystorePBKDF2(t *testing.T) {
+	keyBytes := bytes.Repeat([]byte{0x33}, 32)
+	salt := bytes.Repeat([]byte{0x44}, 32)
+	iv := bytes.Repeat([]byte{0x55}, 16)
+	derivedKey := pbkdf2.Key([]byte("password"), salt, 1024, 32, sha256.New)
+	cipherText, _ := aesCTR(derivedKey[:16], iv, keyBytes)
+
+	keyjson, _ := json.Marshal(&Keystore{
+		Crypto: CryptoJSON{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: CipherParams{IV: hex.EncodeToString(iv)},
+			KDF:          "pbkdf2",
+			KDFParams:    map[string]interface{}{"c": 1024, "dklen": 32, "prf": "hmac-sha256", "salt": hex.EncodeToString(salt)},
+			MAC:          hex.EncodeToString(crypto.Keccak256(derivedKey[16:32], cipherText)),
+		},
+		Version: 3,
+	})
+	key, err := DecryptKey(keyjson, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(FromECDSA(key), keyBytes) {
+		t.Fatalf("decrypted key %x", FromECDSA(key))
+	}
+}
+
+// Web3 Secret Storage Definition 中的 pbkdf2 测试向量
+func TestKeystoreVector(t *testing.T) {
+	keyjson := []byte(`{
+		"crypto": {
+			"cipher": "aes-128-ctr",
+			"cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+			"ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+			"kdf": "pbkdf2",
+			"kdfparams": {
+				"c": 262144,
+				"dklen": 32,
+				"prf": "hmac-sha256",
+				"salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"
+			},
+			"mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+		},
+		"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version": 3
+	}`)
+	key, err := DecryptKey(keyjson, "testpassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := hex.EncodeToString(FromECDSA(key)); have != "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d" {
+		t.Fatalf("decrypted key %s", have)
+	}
+}
+
+func TestKeystoreLimits(t *testing.T) {
+	salt := hex.EncodeToString(bytes.Repeat([]byte{0x44}, 32))
+	tests := []struct {
+		kdf    string
+		params map[string]interface{}
+	}{
+		{"scrypt", map[string]interface{}{"n": 1 << 24, "r": 8, "p": 1, "dklen": 32, "salt": salt}},
+		{"scrypt", map[string]interface{}{"n": 1 << 12, "r": 1024, "p": 1, "dklen": 32, "salt": salt}},
+		{"scrypt", map[string]interface{}{"n": 1 << 12, "r": 8, "p": 1 << 20, "dklen": 32, "salt": salt}},
+		{"scrypt", map[string]interface{}{"n": 1 << 12, "r": 8, "p": 1, "dklen": 1 << 30, "salt": salt}},
+		{"scrypt", map[string]interface{}{"n": 1e300, "r": 8, "p": 1, "dklen": 32, "salt": salt}},
+		{"scrypt", map[string]interface{}{"n": 4096.5, "r": 8, "p": 1, "dklen": 32, "salt": salt}},
+		{"pbkdf2", map[string]interface{}{"c": 1 << 30, "dklen": 32, "prf": "hmac-sha256", "salt": salt}},
+		{"pbkdf2", map[string]interface{}{"c": 1024, "dklen": 64, "prf": "hmac-sha256", "salt": salt}},
+	}
+	for i, tt := range tests {
+		keyjson, _ := json.Marshal(&Keystore{
+			Crypto: CryptoJSON{
+				Cipher:       "aes-128-ctr",
+				CipherText:   hex.EncodeToString(bytes.Repeat([]byte{0x33}, 32)),
+				CipherParams: CipherParams{IV: hex.EncodeToString(bytes.Repeat([]byte{0x55}, 16))},
+				KDF:          tt.kdf,
+				KDFParams:    tt.params,
+				MAC:          hex.EncodeToString(bytes.Repeat([]byte{0x66}, 32)),
+			},
+			Version: 3,
+		})
+		if _, err := DecryptKey(keyjson, "password"); err == nil || err == ErrDecrypt {
+			t.Errorf("test %d: expected parameter error, have %v", i, err)
+		}
+	}
+}
+
+func TestToECDSA(t *testing.T) {
+	for _, keyBytes := range [][]byte{nil, make([]byte, 32), bytes.Repeat([]byte{0xff}, 32), make([]byte, 31)} {
+		if _, err := ToECDSA(keyBytes); err == nil {
+			t.Errorf("ToECDSA(%x) expected error", keyBytes)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package keystore
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// scrypt 密钥派生 (RFC 7914), vendor 中的 golang.org/x/crypto 不含 scrypt
+
+const maxInt = int(^uint(0) >> 1)
+
+// scryptKey 由口令和盐派生 keyLen 字节的密钥, N 为大于 1 的 2 的幂, r*p < 2^30
+func scryptKey(password, salt []byte, N, r, p, keyLen int) ([]byte, error) {
+	if N <= 1 || N&(N-1) != 0 {
+		return nil, errors.New("scrypt: N must be > 1 and a power of 2")
+	}
+	if r <= 0 || p <= 0 || uint64(r)*uint64(p) >= 1<<30 || r > maxInt/128/p || r > maxInt/256 || N > maxInt/128/r {
+		return nil, errors.New("scrypt: parameters are too large")
+	}
+
+	xy := make([]uint32, 64*r)
+	v := make([]uint32, 32*N*r)
+	b := pbkdf2.Key(password, salt, 1, p*128*r, sha256.New)
+	for i := 0; i < p; i++ {
+		smix(b[i*128*r:], r, N, v, xy)
+	}
+	return pbkdf2.Key(password, b, 1, keyLen, sha256.New), nil
+}
+
+// smix ROMix, 结果写回 b
+func smix(b []byte, r, N int, v, xy []uint32) {
+	var tmp [16]uint32
+	R := 32 * r
+	x := xy
+	y := xy[R:]
+
+	for i := 0; i < R; i++ {
+		x[i] = binary.LittleEndian.Uint32(b[i*4:])
+	}
+	for i := 0; i < N; i += 2 {
+		copy(v[i*R:], x[:R])
+		blockMix(&tmp, x, y, r)
+
+		copy(v[(i+1)*R:], y[:R])
+		blockMix(&tmp, y, x, r)
+	}
+	for i := 0; i < N; i += 2 {
+		j := int(integer(x, r) & uint64(N-1))
+		blockXOR(x, v[j*R:], R)
+		blockMix(&tmp, x, y, r)
+
+		j = int(integer(y, r) & uint64(N-1))
+		blockXOR(y, v[j*R:], R)
+		blockMix(&tmp, y, x, r)
+	}
+	for i, w := range x[:R] {
+		binary.LittleEndian.PutUint32(b[i*4:], w)
+	}
+}
+
+func blockXOR(dst, src []uint32, n int) {
+	for i, w := range src[:n] {
+		dst[i] ^= w
+	}
+}
+
+// blockMix BlockMix(Salsa20/8), 偶数块写入前半部分, 奇数块写入后半部分
+func blockMix(tmp *[16]uint32, in, out []uint32, r int) {
+	copy(tmp[:], in[(2*r-1)*16:])
+	for i := 0; i < 2*r; i += 2 {
+		salsaXOR(tmp, in[i*16:], out[i*8:])
+		salsaXOR(tmp, in[i*16+16:], out[i*8+r*16:])
+	}
+}
+
+// integer Integerify, 最后一个 64 字节块的前 8 字节
+func integer(b []uint32, r int) uint64 {
+	j := (2*r - 1) * 16
+	return uint64(b[j]) | uint64(b[j+1])<<32
+}
+
+// salsaXOR 对 tmp 与 in 的异或结果执行 Salsa20/8, 结果写入 tmp 和 out
+func salsaXOR(tmp *[16]uint32, in, out []uint32) {
+	var w, x [16]uint32
+	for i := range w {
+		w[i] = tmp[i] ^ in[i]
+	}
+	x = w
+	for i := 0; i < 8; i += 2 {
+		quarter(&x, 0, 4, 8, 12)
+		quarter(&x, 5, 9, 13, 1)
+		quarter(&x, 10, 14, 2, 6)
+		quarter(&x, 15, 3, 7, 11)
+
+		quarter(&x, 0, 1, 2, 3)
+		quarter(&x, 5, 6, 7, 4)
+		quarter(&x, 10, 11, 8, 9)
+		quarter(&x, 15, 12, 13, 14)
+	}
+	for i := range x {
+		x[i] += w[i]
+		out[i] = x[i]
+		tmp[i] = x[i]
+	}
+}
+
+// quarter Salsa20 quarter-round
+func quarter(x *[16]uint32, a, b, c, d int) {
+	x[b] ^= bits.RotateLeft32(x[a]+x[d], 7)
+	x[c] ^= bits.RotateLeft32(x[b]+x[a], 9)
+	x[d] ^= bits.RotateLeft32(x[c]+x[b], 13)
+	x[a] ^= bits.RotateLeft32(x[d]+x[c], 18)
+}
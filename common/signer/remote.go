@@ -0,0 +1,225 @@
+package signer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/erick785/services/common/wallet"
+)
+
+// 签名服务接口, 请求及响应均为 JSON, 令牌通过 Authorization: Bearer 传递
+const (
+	PathPublicKey      = "/publickey"
+	PathSignTx         = "/signtx"
+	PathExportKeystore = "/exportkeystore"
+	PathImportKeystore = "/importkeystore"
+	PathCreateWallet   = "/createwallet"
+	PathImportWallet   = "/importwallet"
+	PathExportMnemonic = "/exportmnemonic"
+	PathRenameWallet   = "/renamewallet"
+)
+
+// knownErrors 签名服务返回时还原的错误, 调用方按错误值区分处理
+var knownErrors = []error{
+	wallet.ErrWalletExists,
+	wallet.ErrWalletInUse,
+	wallet.ErrWatchOnly,
+	wallet.ErrPassphrase,
+	wallet.ErrNoEntropy,
+}
+
+// Request 签名服务请求
+type Request struct {
+	Wallet     string `json:"wallet"`               // 用户名
+	Key        string `json:"key,omitempty"`        // 派生路径(如 m/44'/60'/0'/0/0)或导入私钥的地址
+	Tx         string `json:"tx,omitempty"`         // 待签名交易 RLP 编码(十六进制)
+	Keystore   string `json:"keystore,omitempty"`   // 待导入的 v3 格式私钥 JSON
+	Password   string `json:"password,omitempty"`   // 私钥导出/导入口令
+	Passphrase string `json:"passphrase,omitempty"` // BIP39 口令
+	Entropy    string `json:"entropy,omitempty"`    // 导入的十六进制商
+	Overwrite  bool   `json:"overwrite,omitempty"`  // 覆盖已有钱包
+	Replace    bool   `json:"replace,omitempty"`    // 允许替换不同的商
+	NewWallet  string `json:"new_wallet,omitempty"` // 新用户名
+}
+
+// Response 签名服务响应
+type Response struct {
+	PublicKey string `json:"public_key,omitempty"` // 压缩公钥(十六进制)
+	Tx        string `json:"tx,omitempty"`         // 已签名交易 RLP 编码(十六进制)
+	Keystore  string `json:"keystore,omitempty"`   // 导出的 v3 格式私钥 JSON
+	Mnemonic  string `json:"mnemonic,omitempty"`   // 导出的 BIP39 助记词
+	Error     string `json:"error,omitempty"`      // 错误信息
+}
+
+// RemoteSigner 通过 HTTP/JSON 调用独立的签名服务, 商及私钥不进入当前进程
+type RemoteSigner struct {
+	URL   string // 签名服务地址, 如 http://127.0.0.1:8090
+	Token string // 访问令牌
+
+	client *http.Client
+}
+
+// NewRemoteSigner 创建远程签名
+func NewRemoteSigner(url string, token string, timeout time.Duration) *RemoteSigner {
+	return &RemoteSigner{
+		URL:   strings.TrimRight(url, "/"),
+		Token: token,
+		client: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+func (signer *RemoteSigner) call(path string, request *Request) (*Response, error) {
+	var buff bytes.Buffer
+	if err := json.NewEncoder(&buff).Encode(request); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", signer.URL+path, &buff)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(signer.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+signer.Token)
+	}
+	resp, err := signer.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("signer %s error --- %s", path, err)
+	}
+	defer resp.Body.Close()
+
+	response := &Response{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, fmt.Errorf("signer %s status %d, decode error --- %s", path, resp.StatusCode, err)
+	}
+	if len(response.Error) > 0 {
+		for _, known := range knownErrors {
+			if response.Error == known.Error() {
+				return nil, known
+			}
+		}
+		return nil, fmt.Errorf("signer %s error --- %s", path, response.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signer %s status %d", path, resp.StatusCode)
+	}
+	return response, nil
+}
+
+func decodePublicKey(response *Response) (*ecdsa.PublicKey, error) {
+	bts, err := hex.DecodeString(strings.TrimPrefix(response.PublicKey, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalPublicKey(bts)
+}
+
+// PublicKey 用户钱包中 key 对应的公钥
+func (signer *RemoteSigner) PublicKey(name string, key string, passphrase string) (*ecdsa.PublicKey, error) {
+	response, err := signer.call(PathPublicKey, &Request{
+		Wallet:     name,
+		Key:        key,
+		Passphrase: passphrase,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodePublicKey(response)
+}
+
+// SignTx 以用户钱包中 key 对应的私钥签名交易
+func (signer *RemoteSigner) SignTx(name string, key string, passphrase string, tx []byte) ([]byte, error) {
+	response, err := signer.call(PathSignTx, &Request{
+		Wallet:     name,
+		Key:        key,
+		Tx:         "0x" + hex.EncodeToString(tx),
+		Passphrase: passphrase,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Tx) == 0 {
+		return nil, errors.New("signer returned empty tx")
+	}
+	return hex.DecodeString(strings.TrimPrefix(response.Tx, "0x"))
+}
+
+// ExportKeystore 以口令加密导出 key 对应的私钥
+func (signer *RemoteSigner) ExportKeystore(name string, key string, passphrase string, password string) ([]byte, error) {
+	response, err := signer.call(PathExportKeystore, &Request{
+		Wallet:     name,
+		Key:        key,
+		Password:   password,
+		Passphrase: passphrase,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Keystore) == 0 {
+		return nil, errors.New("signer returned empty keystore")
+	}
+	return []byte(response.Keystore), nil
+}
+
+// ImportKeystore 以口令解密私钥并保存到用户钱包
+func (signer *RemoteSigner) ImportKeystore(name string, keyjson []byte, password string) (*ecdsa.PublicKey, error) {
+	response, err := signer.call(PathImportKeystore, &Request{
+		Wallet:   name,
+		Keystore: string(keyjson),
+		Password: password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodePublicKey(response)
+}
+
+// CreateWallet 用户没有钱包时以新生成的商创建钱包
+func (signer *RemoteSigner) CreateWallet(name string) error {
+	_, err := signer.call(PathCreateWallet, &Request{
+		Wallet: name,
+	})
+	return err
+}
+
+// ImportWallet 以十六进制商导入用户钱包
+func (signer *RemoteSigner) ImportWallet(name string, hexEntropy string, overwrite bool, replace bool) error {
+	_, err := signer.call(PathImportWallet, &Request{
+		Wallet:    name,
+		Entropy:   hexEntropy,
+		Overwrite: overwrite,
+		Replace:   replace,
+	})
+	return err
+}
+
+// ExportMnemonic 用户钱包的 BIP39 助记词
+func (signer *RemoteSigner) ExportMnemonic(name string) (string, error) {
+	response, err := signer.call(PathExportMnemonic, &Request{
+		Wallet: name,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(response.Mnemonic) == 0 {
+		return "", errors.New("signer returned empty mnemonic")
+	}
+	return response.Mnemonic, nil
+}
+
+// RenameWallet 修改用户名
+func (signer *RemoteSigner) RenameWallet(name string, newname string) error {
+	_, err := signer.call(PathRenameWallet, &Request{
+		Wallet:    name,
+		NewWallet: newname,
+	})
+	return err
+}
@@ -0,0 +1,116 @@
+package signer
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/erick785/services/common/log"
+	"github.com/erick785/services/common/wallet"
+)
+
+// NewHandler 签名服务, 以 signer 处理 RemoteSigner 的请求; token 不为空时校验访问令牌
+func NewHandler(signer Signer, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathPublicKey, serve(token, true, func(req *Request) (*Response, error) {
+		pub, err := signer.PublicKey(req.Wallet, req.Key, req.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{PublicKey: "0x" + hex.EncodeToString(MarshalPublicKey(pub))}, nil
+	}))
+	mux.HandleFunc(PathSignTx, serve(token, true, func(req *Request) (*Response, error) {
+		tx, err := hex.DecodeString(strings.TrimPrefix(req.Tx, "0x"))
+		if err != nil {
+			return nil, err
+		}
+		signed, err := signer.SignTx(req.Wallet, req.Key, req.Passphrase, tx)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Tx: "0x" + hex.EncodeToString(signed)}, nil
+	}))
+	mux.HandleFunc(PathExportKeystore, serve(token, true, func(req *Request) (*Response, error) {
+		keyjson, err := signer.ExportKeystore(req.Wallet, req.Key, req.Passphrase, req.Password)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Keystore: string(keyjson)}, nil
+	}))
+	mux.HandleFunc(PathImportKeystore, serve(token, false, func(req *Request) (*Response, error) {
+		pub, err := signer.ImportKeystore(req.Wallet, []byte(req.Keystore), req.Password)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{PublicKey: "0x" + hex.EncodeToString(MarshalPublicKey(pub))}, nil
+	}))
+	mux.HandleFunc(PathCreateWallet, serve(token, false, func(req *Request) (*Response, error) {
+		if err := signer.CreateWallet(req.Wallet); err != nil {
+			return nil, err
+		}
+		return &Response{}, nil
+	}))
+	mux.HandleFunc(PathImportWallet, serve(token, false, func(req *Request) (*Response, error) {
+		if err := signer.ImportWallet(req.Wallet, req.Entropy, req.Overwrite, req.Replace); err != nil {
+			return nil, err
+		}
+		return &Response{}, nil
+	}))
+	mux.HandleFunc(PathExportMnemonic, serve(token, false, func(req *Request) (*Response, error) {
+		mnemonic, err := signer.ExportMnemonic(req.Wallet)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Mnemonic: mnemonic}, nil
+	}))
+	mux.HandleFunc(PathRenameWallet, serve(token, false, func(req *Request) (*Response, error) {
+		if len(req.NewWallet) == 0 {
+			return nil, errors.New("empty new wallet")
+		}
+		if err := signer.RenameWallet(req.Wallet, req.NewWallet); err != nil {
+			return nil, err
+		}
+		return &Response{}, nil
+	}))
+	return mux
+}
+
+// checkKey 校验 key 为合法的派生路径或地址
+func checkKey(key string) error {
+	if IsPath(key) {
+		_, err := wallet.ParseDerivationPath(key)
+		return err
+	}
+	if bts, err := hex.DecodeString(strings.TrimPrefix(key, "0x")); err != nil || len(bts) != 20 || !strings.HasPrefix(key, "0x") {
+		return errors.New("invalid key")
+	}
+	return nil
+}
+
+func serve(token string, withKey bool, handle func(req *Request) (*Response, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := http.StatusOK
+		response := &Response{}
+		req := &Request{}
+		if r.Method != "POST" {
+			status, response.Error = http.StatusMethodNotAllowed, "method not allowed"
+		} else if auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); len(token) > 0 && subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			status, response.Error = http.StatusUnauthorized, "unauthorized"
+		} else if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			status, response.Error = http.StatusBadRequest, err.Error()
+		} else if len(req.Wallet) == 0 || (withKey && checkKey(req.Key) != nil) {
+			status, response.Error = http.StatusBadRequest, "invalid wallet or key"
+		} else if resp, err := handle(req); err != nil {
+			log.Errorf("[Signer] %s %s %s err %v", r.URL.Path, req.Wallet, req.Key, err)
+			status, response.Error = http.StatusInternalServerError, err.Error()
+		} else {
+			response = resp
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	}
+}
@@ -0,0 +1,268 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/erick785/services/common/keystore"
+	"github.com/erick785/services/common/wallet"
+	"github.com/erick785/uranus/common/crypto"
+	"github.com/erick785/uranus/common/rlp"
+	"github.com/erick785/uranus/core/types"
+)
+
+// Signer 交易签名及钱包商的保管, 商及私钥只存在于签名方;
+// key 为 HD 派生路径(如 m/44'/60'/0'/0/0)或导入私钥的地址(0x 开头);
+// passphrase 为 BIP39 口令, 只用于派生路径, 由用户随请求提供, 不保存
+type Signer interface {
+	// PublicKey 用户钱包中 key 对应的公钥
+	PublicKey(name string, key string, passphrase string) (*ecdsa.PublicKey, error)
+	// SignTx 以用户钱包中 key 对应的私钥签名交易, 参数及结果均为 RLP 编码
+	SignTx(name string, key string, passphrase string, tx []byte) ([]byte, error)
+	// ExportKeystore 以口令加密导出 key 对应的私钥, 返回 v3 格式的 JSON
+	ExportKeystore(name string, key string, passphrase string, password string) ([]byte, error)
+	// ImportKeystore 以口令解密 v3 格式的私钥并保存到用户钱包, 返回公钥
+	ImportKeystore(name string, keyjson []byte, password string) (*ecdsa.PublicKey, error)
+	// CreateWallet 用户没有钱包时以新生成的商创建钱包
+	CreateWallet(name string) error
+	// ImportWallet 以十六进制商导入用户钱包, 同 wallet.Mysql.ImportWallet;
+	// replace 为调用方对原地址没有余额及未完成订单的判断, 只在商不同时使用
+	ImportWallet(name string, hexEntropy string, overwrite bool, replace bool) error
+	// ExportMnemonic 用户钱包的 BIP39 助记词
+	ExportMnemonic(name string) (string, error)
+	// RenameWallet 修改用户名, 商以新用户名重新加密
+	RenameWallet(name string, newname string) error
+}
+
+// Account 用户钱包中的一个签名地址
+type Account struct {
+	Signer     Signer
+	Wallet     string           // 用户名
+	Key        string           // HD 派生路径或导入私钥的地址
+	Passphrase string           // BIP39 口令
+	PublicKey  *ecdsa.PublicKey // 公钥
+}
+
+// NewAccount 查询 key 对应的公钥并创建签名地址
+func NewAccount(signer Signer, name string, key string, passphrase string) (*Account, error) {
+	pub, err := signer.PublicKey(name, key, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		Signer:     signer,
+		Wallet:     name,
+		Key:        key,
+		Passphrase: passphrase,
+		PublicKey:  pub,
+	}, nil
+}
+
+// SignTx 签名 RLP 编码的交易
+func (account *Account) SignTx(tx []byte) ([]byte, error) {
+	return account.Signer.SignTx(account.Wallet, account.Key, account.Passphrase, tx)
+}
+
+// IsPath key 是否为 HD 派生路径
+func IsPath(key string) bool {
+	return strings.HasPrefix(key, "m/")
+}
+
+// Address 公钥对应的地址(小写)
+func Address(pub *ecdsa.PublicKey) string {
+	return "0x" + hex.EncodeToString(crypto.PubkeyToAddress(*pub).Bytes())
+}
+
+// WalletStore 用户钱包存储, wallet.Mysql 实现; GetWallet 每次返回新打开的钱包, GetImportedKey 返回的私钥,
+// 均由调用方用后清零; ImportKey 不能保留传入的私钥
+type WalletStore interface {
+	GetWallet(name string) (*wallet.Wallet, error)
+	InsertOrGetWallet(name string) (*wallet.Wallet, error)
+	ImportWallet(name string, hexEntropy string, overwrite bool, idle func(old *wallet.Wallet) (bool, error)) (*wallet.Wallet, error)
+	UpdateWalletName(name string, newname string) error
+	GetImportedKey(name string, address string) ([]byte, error)
+	ImportKey(name string, address string, key []byte) error
+}
+
+// LocalSigner 进程内签名, 由用户钱包 HD 派生私钥或读取导入的私钥
+type LocalSigner struct {
+	store WalletStore
+
+	ScryptN int // 导出私钥的 scrypt 参数
+	ScryptP int
+}
+
+// NewLocalSigner 创建进程内签名
+func NewLocalSigner(store WalletStore) *LocalSigner {
+	return &LocalSigner{
+		store:   store,
+		ScryptN: keystore.StandardScryptN,
+		ScryptP: keystore.StandardScryptP,
+	}
+}
+
+// privateKey 临时取得私钥, 调用方用后以 wallet.ZeroKey 清零
+func (signer *LocalSigner) privateKey(name string, key string, passphrase string) (*ecdsa.PrivateKey, error) {
+	if !IsPath(key) {
+		bts, err := signer.store.GetImportedKey(name, key)
+		if err != nil {
+			return nil, err
+		}
+		if bts == nil {
+			return nil, fmt.Errorf("key %s not found in wallet %s", key, name)
+		}
+		defer zero(bts)
+		return keystore.ToECDSA(bts)
+	}
+
+	path, err := wallet.ParseDerivationPath(key)
+	if err != nil {
+		return nil, err
+	}
+	wlt, err := signer.store.GetWallet(name)
+	if err != nil {
+		return nil, err
+	}
+	if wlt == nil {
+		return nil, fmt.Errorf("wallet %s not found", name)
+	}
+	defer wlt.Zero()
+	if wlt, err = wlt.WithPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+	defer wlt.Zero()
+	return wlt.DerivePrivateKey(path)
+}
+
+// PublicKey 用户钱包中 key 对应的公钥
+func (signer *LocalSigner) PublicKey(name string, key string, passphrase string) (*ecdsa.PublicKey, error) {
+	privateKey, err := signer.privateKey(name, key, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer wallet.ZeroKey(privateKey)
+	pub := privateKey.PublicKey
+	return &pub, nil
+}
+
+// SignTx 以用户钱包中 key 对应的私钥签名交易
+func (signer *LocalSigner) SignTx(name string, key string, passphrase string, txb []byte) ([]byte, error) {
+	tx := &types.Transaction{}
+	if err := rlp.DecodeBytes(txb, tx); err != nil {
+		return nil, err
+	}
+	privateKey, err := signer.privateKey(name, key, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer wallet.ZeroKey(privateKey)
+	if err := tx.SignTx(types.Signer{}, privateKey); err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(tx)
+}
+
+// ExportKeystore 以口令加密导出 key 对应的私钥
+func (signer *LocalSigner) ExportKeystore(name string, key string, passphrase string, password string) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, errors.New("empty password")
+	}
+	privateKey, err := signer.privateKey(name, key, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer wallet.ZeroKey(privateKey)
+	return keystore.EncryptKey(privateKey, password, signer.ScryptN, signer.ScryptP)
+}
+
+// ImportKeystore 以口令解密私钥, 加密保存到已有的用户钱包
+func (signer *LocalSigner) ImportKeystore(name string, keyjson []byte, password string) (*ecdsa.PublicKey, error) {
+	wlt, err := signer.store.GetWallet(name)
+	if err != nil {
+		return nil, err
+	}
+	if wlt == nil {
+		return nil, fmt.Errorf("wallet %s not found", name)
+	}
+	wlt.Zero()
+	privateKey, err := keystore.DecryptKey(keyjson, password)
+	if err != nil {
+		return nil, err
+	}
+	defer wallet.ZeroKey(privateKey)
+	bts := keystore.FromECDSA(privateKey)
+	defer zero(bts)
+	if err := signer.store.ImportKey(name, Address(&privateKey.PublicKey), bts); err != nil {
+		return nil, err
+	}
+	pub := privateKey.PublicKey
+	return &pub, nil
+}
+
+// CreateWallet 用户没有钱包时以新生成的商创建钱包
+func (signer *LocalSigner) CreateWallet(name string) error {
+	wlt, err := signer.store.InsertOrGetWallet(name)
+	if err != nil {
+		return err
+	}
+	wlt.Zero()
+	return nil
+}
+
+// ImportWallet 以十六进制商导入用户钱包, replace 为 false 时不替换不同的商
+func (signer *LocalSigner) ImportWallet(name string, hexEntropy string, overwrite bool, replace bool) error {
+	idle := func(old *wallet.Wallet) (bool, error) {
+		return replace, nil
+	}
+	wlt, err := signer.store.ImportWallet(name, hexEntropy, overwrite, idle)
+	if err != nil {
+		return err
+	}
+	wlt.Zero()
+	return nil
+}
+
+// ExportMnemonic 用户钱包的 BIP39 助记词
+func (signer *LocalSigner) ExportMnemonic(name string) (string, error) {
+	wlt, err := signer.store.GetWallet(name)
+	if err != nil {
+		return "", err
+	}
+	if wlt == nil {
+		return "", fmt.Errorf("wallet %s not found", name)
+	}
+	defer wlt.Zero()
+	return wlt.Mnemonic()
+}
+
+// RenameWallet 修改用户名
+func (signer *LocalSigner) RenameWallet(name string, newname string) error {
+	return signer.store.UpdateWalletName(name, newname)
+}
+
+// MarshalPublicKey 公钥压缩编码
+func MarshalPublicKey(pub *ecdsa.PublicKey) []byte {
+	return (*btcec.PublicKey)(pub).SerializeCompressed()
+}
+
+// UnmarshalPublicKey 解析压缩或非压缩编码的公钥
+func UnmarshalPublicKey(bts []byte) (*ecdsa.PublicKey, error) {
+	if len(bts) == 0 {
+		return nil, errors.New("empty public key")
+	}
+	pub, err := btcec.ParsePubKey(bts, btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+	return pub.ToECDSA(), nil
+}
+
+func zero(bts []byte) {
+	for i := range bts {
+		bts[i] = 0
+	}
+}
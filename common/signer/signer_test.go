@@ -0,0 +1,262 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erick785/services/common/keystore"
+	"github.com/erick785/services/common/wallet"
+	bip39 "github.com/tyler-smith/go-bip39"
+)
+
+type memoryStore struct {
+	wallets map[string]*wallet.Wallet
+	keys    map[string][]byte
+}
+
+// GetWallet 与 wallet.Mysql 相同, 每次返回新打开的钱包, 签名后清零不影响之后的请求
+func (store *memoryStore) GetWallet(name string) (*wallet.Wallet, error) {
+	wlt, ok := store.wallets[name]
+	if !ok {
+		return nil, nil
+	}
+	return wallet.NewWallet(wlt.Name, hex.EncodeToString(wlt.Entropy()), wlt.Meta)
+}
+
+func (store *memoryStore) InsertOrGetWallet(name string) (*wallet.Wallet, error) {
+	if _, ok := store.wallets[name]; !ok {
+		wlt, err := wallet.NewWallet(name, wallet.NewHexEntropy(), nil)
+		if err != nil {
+			return nil, err
+		}
+		store.wallets[name] = wlt
+	}
+	return store.GetWallet(name)
+}
+
+func (store *memoryStore) ImportWallet(name string, hexEntropy string, overwrite bool, idle func(old *wallet.Wallet) (bool, error)) (*wallet.Wallet, error) {
+	if old, ok := store.wallets[name]; ok {
+		if !overwrite {
+			return nil, wallet.ErrWalletExists
+		}
+		if hex.EncodeToString(old.Entropy()) != hexEntropy {
+			if ok, err := idle(old); err != nil || !ok {
+				return nil, wallet.ErrWalletInUse
+			}
+		}
+	}
+	wlt, err := wallet.NewWallet(name, hexEntropy, nil)
+	if err != nil {
+		return nil, err
+	}
+	store.wallets[name] = wlt
+	return store.GetWallet(name)
+}
+
+func (store *memoryStore) UpdateWalletName(name string, newname string) error {
+	if wlt, ok := store.wallets[name]; ok {
+		delete(store.wallets, name)
+		wlt.Name = newname
+		store.wallets[newname] = wlt
+	}
+	return nil
+}
+
+func (store *memoryStore) GetImportedKey(name string, address string) ([]byte, error) {
+	key := store.keys[name+"|"+address]
+	if key == nil {
+		return nil, nil
+	}
+	return append([]byte{}, key...), nil
+}
+
+func (store *memoryStore) ImportKey(name string, address string, key []byte) error {
+	store.keys[name+"|"+address] = append([]byte{}, key...)
+	return nil
+}
+
+// echoSigner 签名结果为 用户名|路径|口令|交易, 用于校验请求转发
+type echoSigner struct {
+	*LocalSigner
+}
+
+func (signer *echoSigner) SignTx(name string, key string, passphrase string, tx []byte) ([]byte, error) {
+	return []byte(fmt.Sprintf("%s|%s|%s|%x", name, key, passphrase, tx)), nil
+}
+
+func newStore(t *testing.T) *memoryStore {
+	entropy, err := bip39.EntropyFromMnemonic("abandon amount liar amount expire adjust cage candy arch gather drum buyer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wlt, err := wallet.NewWallet("test", hex.EncodeToString(entropy), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &memoryStore{
+		wallets: map[string]*wallet.Wallet{"test": wlt},
+		keys:    make(map[string][]byte),
+	}
+}
+
+func TestRemoteSigner(t *testing.T) {
+	local := NewLocalSigner(newStore(t))
+	server := httptest.NewServer(NewHandler(&echoSigner{local}, "secret"))
+	defer server.Close()
+	remote := NewRemoteSigner(server.URL, "secret", 5*time.Second)
+
+	path := "m/44'/60'/0'/0/1"
+	pub, err := local.PublicKey("test", path, "25th")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, _ := local.PublicKey("test", path, ""); bytes.Equal(MarshalPublicKey(plain), MarshalPublicKey(pub)) {
+		t.Fatal("passphrase derived the same public key")
+	}
+	account, err := NewAccount(remote, "test", path, "25th")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(MarshalPublicKey(account.PublicKey), MarshalPublicKey(pub)) {
+		t.Fatalf("public key %x, expected %x", MarshalPublicKey(account.PublicKey), MarshalPublicKey(pub))
+	}
+
+	signed, err := account.SignTx([]byte{0xc0, 0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(signed) != "test|m/44'/60'/0'/0/1|25th|c001" {
+		t.Fatalf("signed %s", signed)
+	}
+
+	if _, err := remote.PublicKey("unknown", path, ""); err == nil {
+		t.Fatal("unknown wallet expected error")
+	}
+	if _, err := remote.PublicKey("test", "m/44'/60'/x", ""); err == nil {
+		t.Fatal("invalid key expected error")
+	}
+	if _, err := NewRemoteSigner(server.URL, "wrong", 5*time.Second).PublicKey("test", path, ""); err == nil {
+		t.Fatal("wrong token expected error")
+	}
+}
+
+func TestKeystore(t *testing.T) {
+	store := newStore(t)
+	local := NewLocalSigner(store)
+	local.ScryptN, local.ScryptP = keystore.LightScryptN, keystore.LightScryptP
+	server := httptest.NewServer(NewHandler(local, ""))
+	defer server.Close()
+	remote := NewRemoteSigner(server.URL, "", 5*time.Second)
+
+	//导出 HD 派生私钥, 以口令导入后可用地址签名
+	path := "m/44'/60'/0'/0/3"
+	pub, err := local.PublicKey("test", path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := remote.ExportKeystore("test", path, "", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remote.ExportKeystore("test", path, "", ""); err == nil {
+		t.Fatal("empty password expected error")
+	}
+	if _, err := remote.ImportKeystore("test", keyjson, "wrong"); err == nil {
+		t.Fatal("wrong password expected error")
+	}
+	if _, err := remote.ImportKeystore("unknown", keyjson, "pass"); err == nil {
+		t.Fatal("unknown wallet expected error")
+	}
+	imported, err := remote.ImportKeystore("test", keyjson, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(MarshalPublicKey(imported), MarshalPublicKey(pub)) {
+		t.Fatalf("imported public key %x, expected %x", MarshalPublicKey(imported), MarshalPublicKey(pub))
+	}
+	address := Address(imported)
+	if len(store.keys) != 1 || store.keys["test|"+address] == nil {
+		t.Fatalf("imported keys %v", store.keys)
+	}
+	account, err := NewAccount(remote, "test", address, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(MarshalPublicKey(account.PublicKey), MarshalPublicKey(pub)) {
+		t.Fatalf("account public key %x, expected %x", MarshalPublicKey(account.PublicKey), MarshalPublicKey(pub))
+	}
+	if _, err := remote.PublicKey("test", "0x"+strings.Repeat("ab", 20), ""); err == nil {
+		t.Fatal("unknown address expected error")
+	}
+}
+
+func TestRemoteWallet(t *testing.T) {
+	store := newStore(t)
+	server := httptest.NewServer(NewHandler(NewLocalSigner(store), "secret"))
+	defer server.Close()
+	remote := NewRemoteSigner(server.URL, "secret", 5*time.Second)
+
+	//商只在签名方创建及导出
+	if err := remote.CreateWallet("alice"); err != nil {
+		t.Fatal(err)
+	}
+	created := hex.EncodeToString(store.wallets["alice"].Entropy())
+	if err := remote.CreateWallet("alice"); err != nil || hex.EncodeToString(store.wallets["alice"].Entropy()) != created {
+		t.Fatalf("create existing wallet %v", err)
+	}
+	mnemonic, err := remote.ExportMnemonic("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entropy, err := bip39.EntropyFromMnemonic(mnemonic); err != nil || hex.EncodeToString(entropy) != created {
+		t.Fatalf("mnemonic %s %v", mnemonic, err)
+	}
+	if _, err := remote.ExportMnemonic("unknown"); err == nil {
+		t.Fatal("unknown wallet expected error")
+	}
+
+	//导入的错误还原为 wallet 包的错误值
+	other := wallet.NewHexEntropy()
+	if err := remote.ImportWallet("alice", other, false, true); err != wallet.ErrWalletExists {
+		t.Fatalf("import without overwrite %v", err)
+	}
+	if err := remote.ImportWallet("alice", other, true, false); err != wallet.ErrWalletInUse {
+		t.Fatalf("import without replace %v", err)
+	}
+	if err := remote.ImportWallet("alice", created, true, false); err != nil {
+		t.Fatalf("import same entropy %v", err)
+	}
+	if err := remote.ImportWallet("alice", other, true, true); err != nil || hex.EncodeToString(store.wallets["alice"].Entropy()) != other {
+		t.Fatalf("import replace %v", err)
+	}
+
+	if err := remote.RenameWallet("alice", ""); err == nil {
+		t.Fatal("empty new name expected error")
+	}
+	if err := remote.RenameWallet("alice", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.wallets["bob"]; !ok {
+		t.Fatal("renamed wallet expected")
+	}
+}
+
+func TestPublicKeyEncoding(t *testing.T) {
+	local := NewLocalSigner(newStore(t))
+	pub, err := local.PublicKey("test", "m/44'/60'/0'/0/0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := UnmarshalPublicKey(MarshalPublicKey(pub))
+	if err != nil || parsed.X.Cmp(pub.X) != 0 || parsed.Y.Cmp(pub.Y) != 0 {
+		t.Fatalf("unmarshal %v %v", parsed, err)
+	}
+	if _, err := UnmarshalPublicKey([]byte{0x02, 0x01}); err == nil {
+		t.Fatal("invalid public key expected error")
+	}
+}
@@ -0,0 +1,56 @@
+package unit
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Format 按精度将最小单位金额转换为十进制字符串
+func Format(value *big.Int, decimal int64) string {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(decimal), nil)
+	str := new(big.Rat).SetFrac(value, unit).FloatString(int(decimal))
+	if strings.Contains(str, ".") {
+		str = strings.TrimRight(strings.TrimRight(str, "0"), ".")
+	}
+	return str
+}
+
+// Parse 按精度将十进制字符串转换为最小单位金额, 小数位数超过精度时报错
+func Parse(str string, decimal int64) (*big.Int, error) {
+	parts := strings.Split(strings.TrimSpace(str), ".")
+	if len(parts) > 2 || len(parts[0])+len(parts[len(parts)-1]) == 0 {
+		return nil, fmt.Errorf("invalid decimal %s", str)
+	}
+	for _, part := range parts {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return nil, fmt.Errorf("invalid decimal %s", str)
+			}
+		}
+	}
+	frac := ""
+	if len(parts) == 2 {
+		frac = strings.TrimRight(parts[1], "0")
+	}
+	if int64(len(frac)) > decimal {
+		return nil, fmt.Errorf("decimal %s has more than %d fractional digits", str, decimal)
+	}
+	value, _ := new(big.Int).SetString(parts[0]+frac+strings.Repeat("0", int(decimal)-len(frac)), 10)
+	return value, nil
+}
+
+// Amount 请求中的金额: 指定十进制字符串时按精度转换, 与整数金额同时指定时需一致
+func Amount(raw *big.Int, str string, decimal int64) (*big.Int, error) {
+	if len(str) == 0 {
+		return raw, nil
+	}
+	value, err := Parse(str, decimal)
+	if err != nil {
+		return nil, err
+	}
+	if raw.Sign() != 0 && raw.Cmp(value) != 0 {
+		return nil, fmt.Errorf("amount %v and decimal %s mismatch", raw, str)
+	}
+	return value, nil
+}
@@ -0,0 +1,95 @@
+package unit
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		str     string
+		decimal int64
+		value   string
+	}{
+		{"1", 18, "1000000000000000000"},
+		{"1.5", 18, "1500000000000000000"},
+		{"0.000000000000000001", 18, "1"},
+		{" 12.340 ", 2, "1234"},
+		{".5", 6, "500000"},
+		{"3.", 6, "3000000"},
+		{"7", 0, "7"},
+		{"7.000", 0, "7"},
+		{"115792089237316195423570985008687907853269984665640564039457584007913129639935", 0,
+			"115792089237316195423570985008687907853269984665640564039457584007913129639935"},
+	}
+	for i, tt := range tests {
+		value, err := Parse(tt.str, tt.decimal)
+		if err != nil {
+			t.Errorf("test %d: parse %q error: %v", i, tt.str, err)
+		} else if value.String() != tt.value {
+			t.Errorf("test %d: parse %q mismatch: have %v, want %s", i, tt.str, value, tt.value)
+		}
+	}
+
+	invalids := []struct {
+		str     string
+		decimal int64
+	}{
+		{"", 18},
+		{".", 18},
+		{"-1", 18},
+		{"+1", 18},
+		{"1e18", 18},
+		{"1.2.3", 18},
+		{"0x10", 18},
+		{"1,5", 18},
+		{"0.001", 2},
+		{"1.5", 0},
+	}
+	for i, tt := range invalids {
+		if value, err := Parse(tt.str, tt.decimal); err == nil {
+			t.Errorf("invalid %d: expected error for %q, have %v", i, tt.str, value)
+		}
+	}
+}
+
+func TestAmount(t *testing.T) {
+	raw := big.NewInt(1500)
+	if value, err := Amount(raw, "", 3); err != nil || value != raw {
+		t.Errorf("raw amount mismatch: have %v (%v)", value, err)
+	}
+	if value, err := Amount(big.NewInt(0), "1.5", 3); err != nil || value.Cmp(raw) != 0 {
+		t.Errorf("decimal amount mismatch: have %v (%v)", value, err)
+	}
+	if value, err := Amount(raw, "1.5", 3); err != nil || value.Cmp(raw) != 0 {
+		t.Errorf("matching amounts mismatch: have %v (%v)", value, err)
+	}
+	if _, err := Amount(raw, "1.6", 3); err == nil {
+		t.Error("expected error for mismatched amounts")
+	}
+	if _, err := Amount(raw, "abc", 3); err == nil {
+		t.Error("expected error for invalid decimal")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		value   int64
+		decimal int64
+		str     string
+	}{
+		{1500, 3, "1.5"},
+		{1000, 3, "1"},
+		{1, 3, "0.001"},
+		{0, 3, "0"},
+		{7, 0, "7"},
+	}
+	for i, tt := range tests {
+		if str := Format(big.NewInt(tt.value), tt.decimal); str != tt.str {
+			t.Errorf("test %d: have %s, want %s", i, str, tt.str)
+		}
+		if value, err := Parse(tt.str, tt.decimal); err != nil || value.Int64() != tt.value {
+			t.Errorf("test %d: round trip have %v (%v)", i, value, err)
+		}
+	}
+}
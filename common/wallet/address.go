@@ -0,0 +1,59 @@
+package wallet
+
+import (
+	"encoding/json"
+)
+
+// metaAddresses 已分配地址索引在 meta 中的键
+const metaAddresses = "addresses"
+
+// AddressIndex HD 地址索引, 对应路径 m/44'/coin'/account'/0/index
+type AddressIndex struct {
+	Account    uint32 `json:"account"`
+	Index      uint32 `json:"index"`
+	Passphrase string `json:"passphrase,omitempty"` // 所属口令的标识, 为空时不使用口令
+	Address    string `json:"address,omitempty"`    // 口令地址(小写), 没有口令时无法派生, 分配时记录
+}
+
+// AddressIndexes 已分配的地址索引, 默认地址 0/0 总是第一个
+func (wallet *Wallet) AddressIndexes() []*AddressIndex {
+	wallet.RLock()
+	defer wallet.RUnlock()
+	return wallet.addressIndexes()
+}
+
+func (wallet *Wallet) addressIndexes() []*AddressIndex {
+	indexes := []*AddressIndex{}
+	if wallet.Meta != nil {
+		//meta 从数据库读出后为通用类型, 通过 json 转换
+		if bts, err := json.Marshal(wallet.Meta[metaAddresses]); err == nil {
+			json.Unmarshal(bts, &indexes)
+		}
+	}
+	for _, index := range indexes {
+		if index.Account == 0 && index.Index == 0 && len(index.Passphrase) == 0 {
+			return indexes
+		}
+	}
+	return append([]*AddressIndex{{}}, indexes...)
+}
+
+// NextAddressIndex 在当前口令下分配账户下一个地址索引并记录到 meta, 由调用方保存钱包;
+// 设置了口令时由调用方以口令派生并填写 Address
+func (wallet *Wallet) NextAddressIndex(account uint32) *AddressIndex {
+	wallet.Lock()
+	defer wallet.Unlock()
+	indexes := wallet.addressIndexes()
+	//新账户从 0 开始, 已有账户取最大索引加 1
+	next := &AddressIndex{Account: account, Passphrase: wallet.passphrase()}
+	for _, index := range indexes {
+		if index.Account == account && index.Passphrase == next.Passphrase && index.Index >= next.Index {
+			next.Index = index.Index + 1
+		}
+	}
+	if wallet.Meta == nil {
+		wallet.Meta = make(map[string]interface{})
+	}
+	wallet.Meta[metaAddresses] = append(indexes, next)
+	return next
+}
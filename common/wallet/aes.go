@@ -4,19 +4,29 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha1"
+	"crypto/sha256"
 	"errors"
 	"io"
 
 	"golang.org/x/crypto/pbkdf2"
 )
 
-// Encrypt 加密
-func Encrypt(plaintext []byte, key []byte) []byte {
+const (
+	gcmSaltSize = 16 // HKDF 盐长度
+	gcmKeySize  = 32 // AES-256
+)
+
+// gcmInfo HKDF 派生钱包加密密钥的用途标识
+var gcmInfo = []byte("services wallet entropy v2")
+
+// Encrypt 加密(旧格式 AES-CTR, 只在未配置主密钥时使用)
+func Encrypt(plaintext []byte, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	// The IV needs to be unique, but not secure. Therefore it's common to
@@ -30,7 +40,7 @@ func Encrypt(plaintext []byte, key []byte) []byte {
 	// create iv from random stream
 	iv := make([]byte, aes.BlockSize)
 	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		panic(err)
+		return nil, err
 	}
 	stream := cipher.NewCTR(block, iv)
 	stream.XORKeyStream(ciphertext, plaintext)
@@ -38,15 +48,18 @@ func Encrypt(plaintext []byte, key []byte) []byte {
 	result := append(iv, ciphertext...)
 	hmac := pbkdf2.Key([]byte(result), key, 1000, 16, sha1.New)
 	result = append(result, hmac...)
-	return result
+	return result, nil
 }
 
-// Decrypt 解密
+// Decrypt 解密(旧格式 AES-CTR)
 func Decrypt(message []byte, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return []byte{}, err
 	}
+	if len(message) < 32 {
+		return []byte{}, errors.New("message too short")
+	}
 
 	// The first 16 bytes are IV
 	iv := message[0:16]
@@ -64,6 +77,68 @@ func Decrypt(message []byte, key []byte) ([]byte, error) {
 	return plaintext, nil
 }
 
+// SealGCM 以 AES-256-GCM 加密, 密钥由 HKDF-SHA256(secret, 随机盐) 派生, additional 参与认证但不加密;
+// 结果为 {盐} + {nonce} + {密文及认证标签}
+func SealGCM(plaintext []byte, secret []byte, additional []byte) ([]byte, error) {
+	salt := make([]byte, gcmSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(secret, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	result := append(salt, nonce...)
+	return aead.Seal(result, nonce, plaintext, additional), nil
+}
+
+// OpenGCM 解密 SealGCM 的结果, 密钥或 additional 不符时认证失败
+func OpenGCM(message []byte, secret []byte, additional []byte) ([]byte, error) {
+	if len(message) < gcmSaltSize {
+		return nil, errors.New("message too short")
+	}
+	aead, err := newGCM(secret, message[:gcmSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	message = message[gcmSaltSize:]
+	if len(message) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("message too short")
+	}
+	return aead.Open(nil, message[:aead.NonceSize()], message[aead.NonceSize():], additional)
+}
+
+func newGCM(secret []byte, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(hkdf(secret, salt, gcmInfo, gcmKeySize))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// hkdf HKDF-SHA256 (RFC 5869)
+func hkdf(secret []byte, salt []byte, info []byte, length int) []byte {
+	extractor := hmac.New(sha256.New, salt)
+	extractor.Write(secret)
+	prk := extractor.Sum(nil)
+
+	okm := []byte{}
+	var t []byte
+	for i := byte(1); len(okm) < length; i++ {
+		expander := hmac.New(sha256.New, prk)
+		expander.Write(t)
+		expander.Write(info)
+		expander.Write([]byte{i})
+		t = expander.Sum(nil)
+		okm = append(okm, t...)
+	}
+	return okm[:length]
+}
+
 // RightPadBytes zero-pads slice to the right up to length l.
 func RightPadBytes(slice []byte, l int) []byte {
 	if l <= len(slice) {
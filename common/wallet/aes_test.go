@@ -1,12 +1,78 @@
 package wallet
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
 	"testing"
 )
 
 func TestAes(t *testing.T) {
-	bts := Encrypt([]byte("test"), RightPadBytes([]byte("password"), 16))
+	bts, err := Encrypt([]byte("test"), RightPadBytes([]byte("password"), 16))
+	if err != nil {
+		t.Fatal(err)
+	}
 	c, err := Decrypt(bts, RightPadBytes([]byte("password"), 16))
 	fmt.Println(string(c), err)
+
+	if _, err := Encrypt([]byte("test"), []byte("short")); err == nil {
+		t.Fatal("invalid key size expected error")
+	}
+	if _, err := Decrypt(bts[:20], RightPadBytes([]byte("password"), 16)); err == nil {
+		t.Fatal("short message expected error")
+	}
+}
+
+func TestGCM(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	sealed, err := SealGCM([]byte("test"), secret, []byte("13800000000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err := OpenGCM(sealed, secret, []byte("13800000000")); err != nil || string(c) != "test" {
+		t.Fatalf("open %s %v", c, err)
+	}
+	if _, err := OpenGCM(sealed, []byte("fedcba9876543210fedcba9876543210"), []byte("13800000000")); err == nil {
+		t.Fatal("wrong key expected error")
+	}
+	if _, err := OpenGCM(sealed, secret, []byte("13900000000")); err == nil {
+		t.Fatal("wrong additional data expected error")
+	}
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := OpenGCM(tampered, secret, []byte("13800000000")); err == nil {
+		t.Fatal("tampered message expected error")
+	}
+	if _, err := OpenGCM(sealed[:gcmSaltSize+4], secret, []byte("13800000000")); err == nil {
+		t.Fatal("short message expected error")
+	}
+}
+
+// RFC 5869 附录 A.1-A.3 HKDF-SHA256 测试向量
+func TestHKDF(t *testing.T) {
+	seq := func(from, to int) []byte {
+		bts := []byte{}
+		for i := from; i < to; i++ {
+			bts = append(bts, byte(i))
+		}
+		return bts
+	}
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	tests := []struct {
+		ikm, salt, info []byte
+		okm             string
+	}{
+		{ikm, seq(0x00, 0x0d), seq(0xf0, 0xfa),
+			"3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"},
+		{seq(0x00, 0x50), seq(0x60, 0xb0), seq(0xb0, 0x100),
+			"b11e398dc80327a1c8e7f78c596a49344f012eda2d4efad8a050cc4c19afa97c59045a99cac7827271cb41c65e590e09da3275600c2f09b8367793a9aca3db71cc30c58179ec3e87c14c01d5c1f3434f1d87"},
+		{ikm, nil, nil,
+			"8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"},
+	}
+	for i, tt := range tests {
+		okm, _ := hex.DecodeString(tt.okm)
+		if have := hkdf(tt.ikm, tt.salt, tt.info, len(okm)); !bytes.Equal(have, okm) {
+			t.Errorf("test %d: okm mismatch: have %x, want %s", i, have, tt.okm)
+		}
+	}
 }
@@ -0,0 +1,145 @@
+package wallet
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// AddressCache 按用户缓存已派生的地址(path -> address), 按最近使用淘汰;
+// 只缓存公开的地址, 不缓存任何私钥
+type AddressCache struct {
+	size    int                      // 最多缓存的用户数
+	lru     *list.List               // 最近使用的用户在前
+	wallets map[string]*list.Element // name -> *cacheEntry
+	sync.Mutex
+}
+
+type cacheEntry struct {
+	name      string
+	addresses map[string]string // path -> address
+}
+
+// NewAddressCache 创建最多缓存 size 个用户的地址缓存, size 不大于 0 时返回 nil, 即不缓存
+func NewAddressCache(size int) *AddressCache {
+	if size <= 0 {
+		return nil
+	}
+	return &AddressCache{
+		size:    size,
+		lru:     list.New(),
+		wallets: make(map[string]*list.Element),
+	}
+}
+
+// Get 用户路径对应的地址
+func (cache *AddressCache) Get(name string, path string) (string, bool) {
+	if cache == nil {
+		return "", false
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	elem, ok := cache.wallets[name]
+	if !ok {
+		return "", false
+	}
+	cache.lru.MoveToFront(elem)
+	address, ok := elem.Value.(*cacheEntry).addresses[path]
+	return address, ok
+}
+
+// Add 缓存用户路径对应的地址, 超出容量时淘汰最久未使用的用户
+func (cache *AddressCache) Add(name string, path string, address string) {
+	if cache == nil {
+		return
+	}
+	address = strings.ToLower(address)
+	cache.Lock()
+	defer cache.Unlock()
+	elem, ok := cache.wallets[name]
+	if ok {
+		cache.lru.MoveToFront(elem)
+	} else {
+		elem = cache.lru.PushFront(&cacheEntry{name: name, addresses: make(map[string]string)})
+		cache.wallets[name] = elem
+		for cache.lru.Len() > cache.size {
+			cache.remove(cache.lru.Back())
+		}
+	}
+	elem.Value.(*cacheEntry).addresses[path] = address
+}
+
+// Remove 删除用户的缓存, 用户商或用户名变化时调用
+func (cache *AddressCache) Remove(name string) {
+	if cache == nil {
+		return
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	if elem, ok := cache.wallets[name]; ok {
+		cache.remove(elem)
+	}
+}
+
+// Len 缓存的用户数
+func (cache *AddressCache) Len() int {
+	if cache == nil {
+		return 0
+	}
+	cache.Lock()
+	defer cache.Unlock()
+	return cache.lru.Len()
+}
+
+func (cache *AddressCache) remove(elem *list.Element) {
+	entry := cache.lru.Remove(elem).(*cacheEntry)
+	delete(cache.wallets, entry.name)
+}
+
+// Address 路径对应的已派生地址, 先查钱包自身, 再查地址缓存
+func (wallet *Wallet) Address(path string) (string, bool) {
+	wallet.RLock()
+	address, ok := wallet.addresses[path]
+	cache := wallet.cache
+	wallet.RUnlock()
+	if ok {
+		return address, true
+	}
+	if address, ok = cache.Get(wallet.Name, path); ok {
+		wallet.setAddress(path, address)
+	}
+	return address, ok
+}
+
+// SetAddress 记录路径对应的已派生地址
+func (wallet *Wallet) SetAddress(path string, address string) {
+	if len(address) == 0 {
+		return
+	}
+	wallet.setAddress(path, address)
+	wallet.RLock()
+	cache := wallet.cache
+	wallet.RUnlock()
+	cache.Add(wallet.Name, path, address)
+}
+
+func (wallet *Wallet) setAddress(path string, address string) {
+	dpath, err := ParseDerivationPath(path)
+	if err != nil {
+		return
+	}
+	address = strings.ToLower(address)
+	wallet.Lock()
+	defer wallet.Unlock()
+	wallet.addresses[path] = address
+	wallet.paths[address] = dpath
+}
+
+// AddressPath 已派生地址对应的路径, 只包含本钱包已查询或记录过的地址
+func (wallet *Wallet) AddressPath(address string) (DerivationPath, bool) {
+	address = strings.ToLower(address)
+	wallet.RLock()
+	defer wallet.RUnlock()
+	dpath, ok := wallet.paths[address]
+	return dpath, ok
+}
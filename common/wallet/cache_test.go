@@ -0,0 +1,121 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestAddressCache(t *testing.T) {
+	cache := NewAddressCache(2)
+	cache.Add("a", "m/44'/60'/0'/0/0", "0xAAAA")
+	cache.Add("a", "m/44'/60'/0'/0/1", "0xaaa1")
+	cache.Add("b", "m/44'/60'/0'/0/0", "0xbbbb")
+	if address, ok := cache.Get("a", "m/44'/60'/0'/0/0"); !ok || address != "0xaaaa" {
+		t.Fatalf("get %s %v", address, ok)
+	}
+	if address, ok := cache.Get("a", "m/44'/60'/0'/0/1"); !ok || address != "0xaaa1" {
+		t.Fatalf("get %s %v", address, ok)
+	}
+
+	//a 最近使用, 加入 c 时淘汰 b
+	cache.Add("c", "m/44'/60'/0'/0/0", "0xcccc")
+	if cache.Len() != 2 {
+		t.Fatalf("len %d", cache.Len())
+	}
+	if _, ok := cache.Get("b", "m/44'/60'/0'/0/0"); ok {
+		t.Fatal("b expected evicted")
+	}
+
+	cache.Remove("a")
+	if _, ok := cache.Get("a", "m/44'/60'/0'/0/0"); ok {
+		t.Fatal("a expected removed")
+	}
+	if _, ok := cache.Get("c", "m/44'/60'/0'/0/0"); !ok {
+		t.Fatal("c expected cached")
+	}
+
+	//容量为 0 时不缓存
+	disabled := NewAddressCache(0)
+	disabled.Add("a", "m/44'/60'/0'/0/0", "0xaaaa")
+	if _, ok := disabled.Get("a", "m/44'/60'/0'/0/0"); ok || disabled.Len() != 0 {
+		t.Fatal("disabled cache expected empty")
+	}
+}
+
+func TestWalletAddress(t *testing.T) {
+	cache := NewAddressCache(10)
+	w, err := NewWallet("test", NewHexEntropy(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.cache = cache
+	path := "m/44'/60'/0'/0/0"
+	if _, ok := w.Address(path); ok {
+		t.Fatal("address expected not cached")
+	}
+	w.SetAddress(path, "0xABCD")
+	if address, ok := w.Address(path); !ok || address != "0xabcd" {
+		t.Fatalf("address %s %v", address, ok)
+	}
+
+	//同一用户重新打开的钱包从缓存取地址, 取得后可按地址查找路径; 其它用户不能取得
+	reopened, _ := NewWallet("test", hex.EncodeToString(w.Entropy()), nil)
+	reopened.cache = cache
+	if _, ok := reopened.AddressPath("0xabcd"); ok {
+		t.Fatal("address path expected unknown before lookup")
+	}
+	if address, ok := reopened.Address(path); !ok || address != "0xabcd" {
+		t.Fatalf("reopened address %s %v", address, ok)
+	}
+	if dpath, ok := reopened.AddressPath("0xabcd"); !ok || dpath.String() != path {
+		t.Fatalf("address path %v %v", dpath, ok)
+	}
+	other, _ := NewWallet("other", hex.EncodeToString(w.Entropy()), nil)
+	other.cache = cache
+	if _, ok := other.Address(path); ok {
+		t.Fatal("other wallet expected no address")
+	}
+	if _, ok := other.AddressPath("0xabcd"); ok {
+		t.Fatal("other wallet expected no path")
+	}
+}
+
+func TestZeroKey(t *testing.T) {
+	w, err := NewWallet("test", NewHexEntropy(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, _ := ParseDerivationPath("m/44'/60'/0'/0/0")
+	key, err := w.DerivePrivateKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := w.DerivePrivateKey(path)
+	pub, _ := w.DerivePublicKey(path)
+	if pub.X.Cmp(key.PublicKey.X) != 0 {
+		t.Fatal("public key mismatch")
+	}
+	ZeroKey(key)
+	if key.D.Sign() != 0 {
+		t.Fatal("key expected zero")
+	}
+	//清零不影响之后的派生
+	if next, _ := w.DerivePrivateKey(path); next.D.Cmp(again.D) != 0 {
+		t.Fatal("derivation changed after zero")
+	}
+	ZeroKey(nil)
+	ZeroKey(&ecdsa.PrivateKey{D: new(big.Int)})
+
+	//Entropy 返回副本, Zero 清零钱包持有的商
+	entropy := w.Entropy()
+	w.Zero()
+	if hex.EncodeToString(w.Entropy()) != strings.Repeat("0", len(entropy)*2) {
+		t.Fatal("entropy expected zero")
+	}
+	if hex.EncodeToString(entropy) == strings.Repeat("0", len(entropy)*2) {
+		t.Fatal("entropy copy expected unchanged")
+	}
+}
@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// metaImported 导入私钥的地址在 meta 中的键
+const metaImported = "imported"
+
+// ImportedKey 导入的私钥, meta 中只记录地址, 私钥加密存储在 t_key 中
+type ImportedKey struct {
+	Address string `json:"address"` // 地址(小写)
+	Time    int64  `json:"time"`    // 导入时间
+}
+
+// ImportedKeys 导入的私钥
+func (wallet *Wallet) ImportedKeys() []*ImportedKey {
+	wallet.RLock()
+	defer wallet.RUnlock()
+	keys := []*ImportedKey{}
+	if wallet.Meta != nil {
+		if bts, err := json.Marshal(wallet.Meta[metaImported]); err == nil {
+			json.Unmarshal(bts, &keys)
+		}
+	}
+	return keys
+}
+
+// AddImportedKey 记录导入私钥的地址, 已存在时返回 false, 由调用方保存钱包
+func (wallet *Wallet) AddImportedKey(address string, t int64) bool {
+	keys := wallet.ImportedKeys()
+	address = strings.ToLower(address)
+	for _, key := range keys {
+		if key.Address == address {
+			return false
+		}
+	}
+	wallet.Lock()
+	defer wallet.Unlock()
+	if wallet.Meta == nil {
+		wallet.Meta = make(map[string]interface{})
+	}
+	wallet.Meta[metaImported] = append(keys, &ImportedKey{
+		Address: address,
+		Time:    t,
+	})
+	return true
+}
+
+// ImportKey 加密保存用户导入的私钥, 以地址作为附加认证数据; 需要配置主密钥
+func (mysql *Mysql) ImportKey(name string, address string, key []byte) error {
+	if mysql.Keys == nil {
+		return errors.New("master key not configured")
+	}
+	address = strings.ToLower(address)
+	sealed, err := mysql.Keys.Seal(address, key)
+	if err != nil {
+		return err
+	}
+	sqlStr := fmt.Sprintf("REPLACE INTO t_key(s_name, s_address, s_key) values('%s','%s','%s')", name, address, sealed)
+	return mysql.execSQL(sqlStr)
+}
+
+// GetImportedKey 用户导入的私钥, 不存在时返回 nil
+func (mysql *Mysql) GetImportedKey(name string, address string) ([]byte, error) {
+	address = strings.ToLower(address)
+	sqlStr := fmt.Sprintf("SELECT s_key FROM t_key where s_name='%s' and s_address='%s'", name, address)
+	var sealed string
+	err := mysql.db.QueryRow(sqlStr).Scan(&sealed)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, _, err := mysql.Keys.Open(address, sealed)
+	return key, err
+}
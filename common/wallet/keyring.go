@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 密文格式: mk2:主密钥版本:hex(盐 + nonce + 密文 + 认证标签), AES-256-GCM, 密钥由 HKDF 派生, 用户名作为附加认证数据;
+// mk1:主密钥版本:hex(iv + 密文 + 校验) 及无前缀的旧格式(以用户名作为密钥)为 AES-CTR, 只读, 写入时升级为 mk2
+const (
+	sealPrefix   = "mk2"
+	legacyPrefix = "mk1"
+)
+
+// ErrNoMasterKey 未配置主密钥, 旧格式以用户标识作为密钥, 只在显式允许时使用
+var ErrNoMasterKey = errors.New("master key not configured, set -masterkeyfile or WALLET_MASTER_KEYS (or -insecurelegacy to keep the legacy format)")
+
+// ErrMasterKeySigner 使用签名服务时主密钥只能配置在签名服务中
+var ErrMasterKeySigner = errors.New("master key must only be configured on the signer service when -signer is set")
+
+// KeyRing 服务端主密钥, 按版本区分; 新数据使用最高版本加密, 其它版本只用于解密
+type KeyRing struct {
+	current uint32
+	keys    map[uint32][]byte
+}
+
+// ParseKeyRing 解析主密钥, 每项为 版本:十六进制密钥, 以换行或逗号分隔, # 开头为注释
+func ParseKeyRing(str string) (*KeyRing, error) {
+	ring := &KeyRing{
+		keys: make(map[uint32][]byte),
+	}
+	for _, line := range strings.FieldsFunc(str, func(r rune) bool { return r == '\n' || r == ',' }) {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid master key entry, expected version:hexkey")
+		}
+		version, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid master key version %s", parts[0])
+		}
+		key, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(parts[1]), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid master key %d --- %s", version, err)
+		}
+		if err := ring.Add(uint32(version), key); err != nil {
+			return nil, err
+		}
+	}
+	if len(ring.keys) == 0 {
+		return nil, errors.New("no master key")
+	}
+	return ring, nil
+}
+
+// LoadKeyRing 从密钥文件或环境变量加载主密钥, 文件优先, 都未设置时返回 nil
+func LoadKeyRing(file string, env string) (*KeyRing, error) {
+	if len(file) > 0 {
+		bts, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		return ParseKeyRing(string(bts))
+	}
+	if str := os.Getenv(env); len(str) > 0 {
+		return ParseKeyRing(str)
+	}
+	return nil, nil
+}
+
+// KeyRingConfigured 是否配置了主密钥文件或环境变量, 不读取密钥
+func KeyRingConfigured(file string, env string) bool {
+	return len(file) > 0 || len(os.Getenv(env)) > 0
+}
+
+// Add 添加主密钥, 密钥至少 16 字节, 版本号最大的为当前密钥
+func (ring *KeyRing) Add(version uint32, key []byte) error {
+	if len(key) < 16 {
+		return fmt.Errorf("master key %d too short, at least 16 bytes", version)
+	}
+	if _, ok := ring.keys[version]; ok {
+		return fmt.Errorf("master key %d duplicated", version)
+	}
+	ring.keys[version] = key
+	if len(ring.keys) == 1 || version > ring.current {
+		ring.current = version
+	}
+	return nil
+}
+
+// Current 当前主密钥版本
+func (ring *KeyRing) Current() uint32 {
+	return ring.current
+}
+
+// rowKey mk1 格式用户的加密密钥 HMAC-SHA256(主密钥, 用户名)
+func rowKey(master []byte, name string) []byte {
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte(name))
+	return mac.Sum(nil)
+}
+
+// Seal 以当前主密钥加密, 未配置主密钥时使用旧格式
+func (ring *KeyRing) Seal(name string, plaintext []byte) (string, error) {
+	if ring == nil {
+		ciphertext, err := Encrypt(plaintext, RightPadBytes([]byte(name), 16))
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(ciphertext), nil
+	}
+	ciphertext, err := SealGCM(plaintext, ring.keys[ring.current], []byte(name))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%s", sealPrefix, ring.current, hex.EncodeToString(ciphertext)), nil
+}
+
+// Open 解密, 兼容 mk1 及旧格式; stale 表示不是当前格式及当前主密钥, 需要重新加密
+func (ring *KeyRing) Open(name string, sealed string) ([]byte, bool, error) {
+	prefix := ""
+	if parts := strings.SplitN(sealed, ":", 2); len(parts) == 2 {
+		prefix = parts[0]
+	}
+	switch prefix {
+	case "":
+		message, err := hex.DecodeString(sealed)
+		if err != nil {
+			return nil, false, err
+		}
+		plaintext, err := Decrypt(message, RightPadBytes([]byte(name), 16))
+		return plaintext, ring != nil, err
+	case sealPrefix, legacyPrefix:
+	default:
+		return nil, false, fmt.Errorf("unknown sealed entropy format %s", prefix)
+	}
+
+	parts := strings.SplitN(sealed, ":", 3)
+	if len(parts) != 3 {
+		return nil, false, errors.New("invalid sealed entropy")
+	}
+	version, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid master key version %s", parts[1])
+	}
+	if ring == nil {
+		return nil, false, fmt.Errorf("master key %d not configured", version)
+	}
+	master, ok := ring.keys[uint32(version)]
+	if !ok {
+		return nil, false, fmt.Errorf("master key %d not configured", version)
+	}
+	message, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return nil, false, err
+	}
+
+	if prefix == legacyPrefix {
+		plaintext, err := Decrypt(message, rowKey(master, name))
+		return plaintext, true, err
+	}
+	plaintext, err := OpenGCM(message, master, []byte(name))
+	return plaintext, uint32(version) != ring.current, err
+}
@@ -0,0 +1,80 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestParseKeyRing(t *testing.T) {
+	ring, err := ParseKeyRing("# keys\n1:00112233445566778899aabbccddeeff\n3:0x" + strings.Repeat("ab", 32) + ",2:" + strings.Repeat("cd", 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ring.Current() != 3 || len(ring.keys) != 3 {
+		t.Fatalf("current %d, keys %d", ring.Current(), len(ring.keys))
+	}
+	for _, str := range []string{"", "# empty", "1", "a:00112233445566778899aabbccddeeff", "1:0011", "1:zz", "1:00112233445566778899aabbccddeeff,1:00112233445566778899aabbccddeeff"} {
+		if _, err := ParseKeyRing(str); err == nil {
+			t.Errorf("ParseKeyRing(%q) expected error", str)
+		}
+	}
+}
+
+func TestKeyRingSeal(t *testing.T) {
+	entropy := []byte(NewHexEntropy())
+	old, _ := ParseKeyRing("1:" + strings.Repeat("11", 32))
+	ring, _ := ParseKeyRing("1:" + strings.Repeat("11", 32) + "\n2:" + strings.Repeat("22", 32))
+
+	//旧格式
+	var nilRing *KeyRing
+	legacy, err := nilRing.Seal("13800000000", entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := hex.DecodeString(legacy); err != nil {
+		t.Fatalf("legacy format %s", legacy)
+	}
+	if bts, stale, err := ring.Open("13800000000", legacy); err != nil || !stale || string(bts) != string(entropy) {
+		t.Fatalf("open legacy %s %v %v", bts, stale, err)
+	}
+
+	//mk1 格式只读, 当前主密钥加密的也需要升级
+	ciphertext, _ := Encrypt(entropy, rowKey(old.keys[1], "13800000000"))
+	mk1 := "mk1:1:" + hex.EncodeToString(ciphertext)
+	if bts, stale, err := old.Open("13800000000", mk1); err != nil || !stale || string(bts) != string(entropy) {
+		t.Fatalf("open mk1 %s %v %v", bts, stale, err)
+	}
+
+	sealed, err := old.Seal("13800000000", entropy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, "mk2:1:") {
+		t.Fatalf("sealed format %s", sealed)
+	}
+	if bts, stale, err := old.Open("13800000000", sealed); err != nil || stale || string(bts) != string(entropy) {
+		t.Fatalf("open current %s %v %v", bts, stale, err)
+	}
+	if bts, stale, err := ring.Open("13800000000", sealed); err != nil || !stale || string(bts) != string(entropy) {
+		t.Fatalf("open rotated %s %v %v", bts, stale, err)
+	}
+	if _, _, err := nilRing.Open("13800000000", sealed); err == nil {
+		t.Fatal("open without master key expected error")
+	}
+	//密文与用户绑定
+	if _, _, err := old.Open("13900000000", sealed); err == nil {
+		t.Fatal("open with other name expected error")
+	}
+	if _, _, err := old.Open("13800000000", "mk9:1:"+sealed[6:]); err == nil {
+		t.Fatal("open unknown format expected error")
+	}
+
+	sealed, _ = ring.Seal("13800000000", entropy)
+	if !strings.HasPrefix(sealed, "mk2:2:") {
+		t.Fatalf("sealed format %s", sealed)
+	}
+	if _, _, err := old.Open("13800000000", sealed); err == nil {
+		t.Fatal("open with unknown version expected error")
+	}
+}
@@ -0,0 +1,94 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	bip39 "github.com/tyler-smith/go-bip39"
+)
+
+// ErrWalletExists 用户已有钱包
+var ErrWalletExists = errors.New("wallet already exists")
+
+// ErrWalletInUse 替换商时原地址仍有余额或未完成的订单
+var ErrWalletInUse = errors.New("wallet in use")
+
+// Mnemonic 钱包的 BIP39 助记词
+func (wallet *Wallet) Mnemonic() (string, error) {
+	if wallet.WatchOnly() {
+		return "", ErrWatchOnly
+	}
+	if wallet.entropy == nil {
+		return "", ErrNoEntropy
+	}
+	return bip39.NewMnemonic(wallet.entropy)
+}
+
+// ParseEntropy 由 BIP39 助记词或十六进制商得到十六进制商, 二者只能指定一个;
+// 助记词校验单词及校验位, 商长度需为 128~256 位且为 32 位的整数倍
+func ParseEntropy(mnemonic string, hexEntropy string) (string, error) {
+	mnemonic = strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+	hexEntropy = strings.TrimPrefix(strings.TrimSpace(hexEntropy), "0x")
+	if (len(mnemonic) == 0) == (len(hexEntropy) == 0) {
+		return "", errors.New("either mnemonic or entropy required")
+	}
+	if len(mnemonic) > 0 {
+		entropy, err := bip39.EntropyFromMnemonic(mnemonic)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(entropy), nil
+	}
+
+	entropy, err := hex.DecodeString(hexEntropy)
+	if err != nil {
+		return "", err
+	}
+	if _, err := bip39.NewMnemonic(entropy); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(entropy), nil
+}
+
+// ImportWallet 以指定的商创建钱包; 用户已有钱包时, overwrite 为 false 返回 ErrWalletExists;
+// 商与原钱包相同时 meta(包括口令地址)不变; 商不同时只有 idle 判断原地址没有余额及未完成的订单才替换商,
+// 否则返回 ErrWalletInUse, idle 为 nil 时不替换; 替换时保留 meta(已分配的地址序号不变, 地址随商改变, 口令地址清除)
+func (mysql *Mysql) ImportWallet(name string, hexEntropy string, overwrite bool, idle func(old *Wallet) (bool, error)) (*Wallet, error) {
+	wallet, err := NewWallet(name, hexEntropy, nil)
+	if err != nil {
+		return nil, err
+	}
+	old, err := mysql.GetWallet(name)
+	if err != nil {
+		return nil, err
+	}
+	if old != nil {
+		defer old.Zero()
+		if !overwrite {
+			return nil, ErrWalletExists
+		}
+		wallet.Meta = old.Meta
+		if !bytes.Equal(old.entropy, wallet.entropy) {
+			if idle == nil {
+				return nil, ErrWalletInUse
+			}
+			if ok, err := idle(old); err != nil {
+				return nil, err
+			} else if !ok {
+				return nil, ErrWalletInUse
+			}
+			//口令地址由原商派生, 随商失效; 观察钱包替换为普通钱包
+			clearPassphrase(wallet.Meta)
+			delete(wallet.Meta, metaXPub)
+		}
+	}
+	if err := mysql.UpdateWallet(wallet); err != nil {
+		return nil, err
+	}
+	//地址随商改变, 清除缓存
+	mysql.Cache.Remove(name)
+	wallet.cache = mysql.Cache
+	return wallet, nil
+}
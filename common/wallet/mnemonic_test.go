@@ -0,0 +1,42 @@
+package wallet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEntropy(t *testing.T) {
+	mnemonic := "abandon amount liar amount expire adjust cage candy arch gather drum buyer"
+	entropy, err := ParseEntropy(" Abandon amount liar amount expire  adjust cage candy arch gather drum buyer\n", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hexEntropy, err := ParseEntropy("", "0x"+entropy); err != nil || hexEntropy != entropy {
+		t.Fatalf("hex entropy %s %v", hexEntropy, err)
+	}
+
+	wlt, err := NewWallet("test", entropy, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if words, err := wlt.Mnemonic(); err != nil || words != mnemonic {
+		t.Fatalf("mnemonic %s %v", words, err)
+	}
+
+	for _, test := range [][2]string{
+		{"", ""},
+		{mnemonic, entropy},
+		//校验位错误
+		{strings.Replace(mnemonic, "buyer", "zoo", 1), ""},
+		//单词不在词表中
+		{strings.Replace(mnemonic, "buyer", "bitcoin", 1), ""},
+		{"abandon amount liar", ""},
+		{"", "zz"},
+		{"", "00112233445566778899aabbccddee"},
+		{"", strings.Repeat("00", 36)},
+	} {
+		if _, err := ParseEntropy(test[0], test[1]); err == nil {
+			t.Errorf("ParseEntropy(%q, %q) expected error", test[0], test[1])
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -21,7 +22,11 @@ type Mysql struct {
 	DBUser string
 	DBPWD  string
 	DBHost string
-	db     *sql.DB
+	Keys   *KeyRing      // 主密钥, 为空时使用旧格式
+	Cache  *AddressCache // 地址缓存, 为空时不缓存
+	// MetaOnly 只读写 meta 及公开信息, 不解密也不保存商, 用于商由签名服务保管的 API 进程
+	MetaOnly bool
+	db       *sql.DB
 }
 
 // Open open a db and create tables if necessary.
@@ -96,12 +101,60 @@ func (mysql *Mysql) GetWallet(name string) (*Wallet, error) {
 	}
 	ometa := make(map[string]interface{})
 	json.Unmarshal([]byte(meta), &ometa)
-	ebts, _ := hex.DecodeString(entropy)
-	bts, err := Decrypt([]byte(ebts), RightPadBytes([]byte(name), 16))
+	return mysql.openWallet(name, entropy, ometa)
+}
+
+// openWallet 解密用户商并创建钱包, 商为空时为观察钱包; MetaOnly 时不解密, 钱包只包含 meta
+func (mysql *Mysql) openWallet(name string, entropy string, meta map[string]interface{}) (*Wallet, error) {
+	if len(entropy) == 0 {
+		xpub, _ := meta[metaXPub].(string)
+		wallet, err := NewWatchWallet(name, xpub, meta)
+		if err != nil {
+			return nil, err
+		}
+		wallet.cache = mysql.Cache
+		return wallet, nil
+	}
+	if mysql.MetaOnly {
+		return &Wallet{
+			Name:      name,
+			Meta:      meta,
+			paths:     make(map[string]DerivationPath),
+			addresses: make(map[string]string),
+			cache:     mysql.Cache,
+		}, nil
+	}
+	bts, _, err := mysql.Keys.Open(name, entropy)
 	if err != nil {
 		return nil, err
 	}
-	return NewWallet(name, string(bts), ometa)
+	defer zero(bts)
+	//解密结果为十六进制商, 直接解码为钱包持有的字节, 不经过字符串
+	decoded := make([]byte, hex.DecodedLen(len(bts)))
+	if _, err := hex.Decode(decoded, bts); err != nil {
+		zero(decoded)
+		return nil, err
+	}
+	wallet, err := newWallet(name, decoded, "", meta)
+	if err != nil {
+		return nil, err
+	}
+	wallet.cache = mysql.Cache
+	return wallet, nil
+}
+
+// sealWallet 加密用户商, 观察钱包没有商, 保存为空; MetaOnly 时不能保存商
+func (mysql *Mysql) sealWallet(name string, wallet *Wallet) (string, error) {
+	if wallet.WatchOnly() {
+		return "", nil
+	}
+	if mysql.MetaOnly {
+		return "", ErrNoEntropy
+	}
+	bts := make([]byte, hex.EncodedLen(len(wallet.entropy)))
+	hex.Encode(bts, wallet.entropy)
+	defer zero(bts)
+	return mysql.Keys.Seal(name, bts)
 }
 
 // InsertOrGetWallet find wallet
@@ -126,14 +179,30 @@ func (mysql *Mysql) UpdateWalletName(name, newname string) error {
 		return err
 	}
 	meta, _ := json.Marshal(wallet.Meta)
-	sqlStr := fmt.Sprintf("UPDATE t_user set s_name='%s', s_entropy='%s', s_meta='%s' where s_name='%s'", newname, hex.EncodeToString(Encrypt([]byte(wallet.HexEntory), RightPadBytes([]byte(newname), 16))), string(meta), wallet.Name)
+	entropy, err := mysql.sealWallet(newname, wallet)
+	if err != nil {
+		return err
+	}
+	sqlStr := fmt.Sprintf("UPDATE t_user set s_name='%s', s_entropy='%s', s_meta='%s' where s_name='%s';", newname, entropy, string(meta), wallet.Name)
+	sqlStr += fmt.Sprintf("UPDATE t_key set s_name='%s' where s_name='%s'", newname, wallet.Name)
+	mysql.Cache.Remove(name)
+	mysql.Cache.Remove(newname)
 	return mysql.execSQL(sqlStr)
 }
 
 // UpdateWallet insert or update wallet
 func (mysql *Mysql) UpdateWallet(wallet *Wallet) error {
 	meta, _ := json.Marshal(wallet.Meta)
-	sqlStr := fmt.Sprintf("REPLACE INTO t_user(s_name, s_entropy, s_meta) values('%s','%s','%s')", wallet.Name, hex.EncodeToString(Encrypt([]byte(wallet.HexEntory), RightPadBytes([]byte(wallet.Name), 16))), string(meta))
+	//商由签名服务保存, 只更新 meta
+	if mysql.MetaOnly && !wallet.WatchOnly() {
+		sqlStr := fmt.Sprintf("UPDATE t_user set s_meta='%s' where s_name='%s'", string(meta), wallet.Name)
+		return mysql.execSQL(sqlStr)
+	}
+	entropy, err := mysql.sealWallet(wallet.Name, wallet)
+	if err != nil {
+		return err
+	}
+	sqlStr := fmt.Sprintf("REPLACE INTO t_user(s_name, s_entropy, s_meta) values('%s','%s','%s')", wallet.Name, entropy, string(meta))
 	return mysql.execSQL(sqlStr)
 }
 
@@ -163,16 +232,99 @@ func (mysql *Mysql) GetWallets() ([]*Wallet, error) {
 		}
 		ometa := make(map[string]interface{})
 		json.Unmarshal([]byte(meta), &ometa)
-		ebts, _ := hex.DecodeString(entropy)
-		bts, err := Decrypt([]byte(ebts), RightPadBytes([]byte(name), 16))
+		wlt, err := mysql.openWallet(name, entropy, ometa)
 		if err != nil {
 			continue
 		}
-		wlt, err := NewWallet(name, string(bts), ometa)
+		wlts = append(wlts, wlt)
+	}
+	return wlts, nil
+}
+
+// RotateKeys 以当前主密钥及当前格式重新加密全部用户及导入的私钥, 按原密文条件更新, 不影响同时进行的写入;
+// 返回重新加密的数量, 解密或更新失败的用户在错误中列出
+func (mysql *Mysql) RotateKeys() (int, error) {
+	if mysql.Keys == nil {
+		return 0, errors.New("master key not configured")
+	}
+	rotated := 0
+	failed := []string{}
+	//additional 为附加认证数据(用户名或导入私钥的地址)
+	reseal := func(label string, additional string, sealed string, update func(resealed string) string) {
+		bts, stale, err := mysql.Keys.Open(additional, sealed)
 		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s(%s)", label, err))
+			return
+		}
+		if !stale {
+			return
+		}
+		resealed, err := mysql.Keys.Seal(additional, bts)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s(%s)", label, err))
+			return
+		}
+		result, err := mysql.db.Exec(update(resealed))
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s(%s)", label, err))
+			return
+		}
+		//已被其它写入更新时跳过
+		if n, _ := result.RowsAffected(); n > 0 {
+			rotated++
+		}
+	}
+
+	entropies, err := mysql.queryStrings("SELECT s_name, s_entropy FROM t_user", 2)
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range entropies {
+		name, entropy := row[0], row[1]
+		//观察钱包没有商
+		if len(entropy) == 0 {
 			continue
 		}
-		wlts = append(wlts, wlt)
+		reseal(name, name, entropy, func(resealed string) string {
+			return fmt.Sprintf("UPDATE t_user set s_entropy='%s' where s_name='%s' and s_entropy='%s'", resealed, name, entropy)
+		})
 	}
-	return wlts, nil
+
+	keys, err := mysql.queryStrings("SELECT s_name, s_address, s_key FROM t_key", 3)
+	if err != nil {
+		return rotated, err
+	}
+	for _, row := range keys {
+		name, address, key := row[0], row[1], row[2]
+		reseal(name+"/"+address, address, key, func(resealed string) string {
+			return fmt.Sprintf("UPDATE t_key set s_key='%s' where s_name='%s' and s_address='%s' and s_key='%s'", resealed, name, address, key)
+		})
+	}
+
+	if len(failed) > 0 {
+		return rotated, fmt.Errorf("%d wallets failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return rotated, nil
+}
+
+// queryStrings 查询 columns 列字符串
+func (mysql *Mysql) queryStrings(sqlStr string, columns int) ([][]string, error) {
+	rows, err := mysql.db.Query(sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := [][]string{}
+	for rows.Next() {
+		row := make([]string, columns)
+		dest := make([]interface{}, columns)
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
 }
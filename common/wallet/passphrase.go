@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// metaPassphrase 当前 BIP39 口令的标识在 meta 中的键; 口令本身不保存, 标识为口令下的默认地址
+const metaPassphrase = "passphrase"
+
+// ErrPassphrase 口令与地址不符
+var ErrPassphrase = errors.New("invalid passphrase")
+
+// WithPassphrase 以 BIP39 口令(第 25 个词)派生的钱包, 只用于派生密钥, 不包含 meta; 口令为空时返回自身
+func (wallet *Wallet) WithPassphrase(passphrase string) (*Wallet, error) {
+	if len(passphrase) == 0 {
+		return wallet, nil
+	}
+	if wallet.WatchOnly() {
+		return nil, ErrWatchOnly
+	}
+	if wallet.entropy == nil {
+		return nil, ErrNoEntropy
+	}
+	return newWallet(wallet.Name, wallet.Entropy(), passphrase, nil)
+}
+
+// Passphrase 当前口令的标识, 未设置口令时为空
+func (wallet *Wallet) Passphrase() string {
+	wallet.RLock()
+	defer wallet.RUnlock()
+	return wallet.passphrase()
+}
+
+func (wallet *Wallet) passphrase() string {
+	if wallet.Meta == nil {
+		return ""
+	}
+	id, _ := wallet.Meta[metaPassphrase].(string)
+	return id
+}
+
+// SetPassphrase 切换当前口令, id 为口令下的默认地址, 为空时取消口令; 之后分配的地址属于该口令,
+// 已分配的地址保持原口令, 由调用方保存钱包
+func (wallet *Wallet) SetPassphrase(id string) {
+	wallet.Lock()
+	defer wallet.Unlock()
+	id = strings.ToLower(id)
+	if wallet.Meta == nil {
+		wallet.Meta = make(map[string]interface{})
+	}
+	if len(id) == 0 {
+		delete(wallet.Meta, metaPassphrase)
+		return
+	}
+	wallet.Meta[metaPassphrase] = id
+	indexes := wallet.addressIndexes()
+	for _, index := range indexes {
+		if index.Passphrase == id && index.Account == 0 && index.Index == 0 {
+			return
+		}
+	}
+	wallet.Meta[metaAddresses] = append(indexes, &AddressIndex{
+		Passphrase: id,
+		Address:    id,
+	})
+}
+
+// clearPassphrase 清除口令及口令地址, 用于替换用户商
+func clearPassphrase(meta map[string]interface{}) {
+	if meta == nil {
+		return
+	}
+	delete(meta, metaPassphrase)
+	indexes := []*AddressIndex{}
+	if bts, err := json.Marshal(meta[metaAddresses]); err == nil {
+		json.Unmarshal(bts, &indexes)
+	}
+	kept := []*AddressIndex{}
+	for _, index := range indexes {
+		if len(index.Passphrase) == 0 {
+			kept = append(kept, index)
+		}
+	}
+	meta[metaAddresses] = kept
+}
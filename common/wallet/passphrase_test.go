@@ -0,0 +1,59 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestPassphrase(t *testing.T) {
+	w, err := NewWallet("test", NewHexEntropy(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, _ := ParseDerivationPath("m/44'/60'/0'/0/0")
+	plain, _ := w.DerivePrivateKey(path)
+	pw, err := w.WithPassphrase("25th word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := pw.DerivePrivateKey(path)
+	if key.D.Cmp(plain.D) == 0 {
+		t.Fatal("passphrase derived the same key")
+	}
+	if same, _ := w.WithPassphrase(""); same != w {
+		t.Fatal("empty passphrase expected the same wallet")
+	}
+	other, _ := w.WithPassphrase("25th word")
+	if okey, _ := other.DerivePrivateKey(path); okey.D.Cmp(key.D) != 0 {
+		t.Fatal("passphrase derivation not deterministic")
+	}
+
+	//设置口令后新地址属于口令, 序号单独计算
+	w.NextAddressIndex(0)
+	w.SetPassphrase("0xABCD")
+	if w.Passphrase() != "0xabcd" {
+		t.Fatalf("passphrase id %s", w.Passphrase())
+	}
+	if next := w.NextAddressIndex(0); next.Index != 1 || next.Passphrase != "0xabcd" {
+		t.Fatalf("next passphrase index %v", next)
+	}
+	indexes := w.AddressIndexes()
+	if len(indexes) != 4 || indexes[0].Passphrase != "" || indexes[2].Address != "0xabcd" {
+		t.Fatalf("indexes %v", indexes)
+	}
+
+	//模拟保存后从数据库读出, 替换商时清除口令地址
+	meta, _ := json.Marshal(w.Meta)
+	ometa := make(map[string]interface{})
+	json.Unmarshal(meta, &ometa)
+	clearPassphrase(ometa)
+	w, _ = NewWallet("test", hex.EncodeToString(w.Entropy()), ometa)
+	if indexes := w.AddressIndexes(); len(indexes) != 2 || w.Passphrase() != "" {
+		t.Fatalf("cleared indexes %v %s", indexes, w.Passphrase())
+	}
+	w.SetPassphrase("")
+	if w.Passphrase() != "" {
+		t.Fatal("passphrase not removed")
+	}
+}
@@ -0,0 +1,71 @@
+package shamir
+
+// GF(2^8) 运算, 不可约多项式 x^8 + x^4 + x^3 + x + 1(0x11b), 生成元 3
+var (
+	expTable [510]byte
+	logTable [256]byte
+)
+
+func init() {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		expTable[i] = x
+		expTable[i+255] = x
+		logTable[x] = byte(i)
+		// x *= 3
+		x ^= xtime(x)
+	}
+}
+
+// xtime 乘以 x(即 2)
+func xtime(a byte) byte {
+	if a&0x80 != 0 {
+		return a<<1 ^ 0x1b
+	}
+	return a << 1
+}
+
+func mul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+int(logTable[b])]
+}
+
+func div(a, b byte) byte {
+	if b == 0 {
+		panic("shamir: division by zero")
+	}
+	if a == 0 {
+		return 0
+	}
+	return expTable[int(logTable[a])+255-int(logTable[b])]
+}
+
+// evaluate 以 Horner 法计算多项式在 x 处的值, coeffs[0] 为常数项
+func evaluate(coeffs []byte, x byte) byte {
+	y := byte(0)
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coeffs[i]
+	}
+	return y
+}
+
+// interpolate 由点 (xs[i], ys[i][j]) 拉格朗日插值, 计算每个字节位置的多项式在 at 处的值
+func interpolate(xs []byte, ys [][]byte, at byte) []byte {
+	result := make([]byte, len(ys[0]))
+	for i := range xs {
+		// l_i(at) = prod (at - x_j) / (x_i - x_j), 加减均为异或
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			basis = mul(basis, div(at^xs[j], xs[i]^xs[j]))
+		}
+		for k := range result {
+			result[k] ^= mul(basis, ys[i][k])
+		}
+	}
+	return result
+}
@@ -0,0 +1,188 @@
+// Package shamir Shamir 秘密分享: 将用户商或主密钥拆分为 N 个分片, 任意 K 个分片可恢复,
+// 少于 K 个分片不泄露秘密的任何信息; 分片带校验和, 恢复后校验秘密摘要
+package shamir
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	version = 1
+	// digestLen 秘密摘要长度, 与秘密一起拆分, 恢复后校验
+	digestLen = 8
+	// MaxShares 分片序号为 GF(256) 中的非零元素
+	MaxShares = 255
+)
+
+var (
+	// ErrChecksum 分片内容与校验和不符
+	ErrChecksum = errors.New("shamir: share checksum mismatch")
+	// ErrIntegrity 恢复的秘密与摘要不符, 分片被篡改或来自不同的拆分
+	ErrIntegrity = errors.New("shamir: recovered secret integrity check failed")
+	// ErrNotEnough 分片数少于门限
+	ErrNotEnough = errors.New("shamir: not enough shares")
+)
+
+// random 随机数来源, 测试时替换
+var random io.Reader = rand.Reader
+
+// Share 秘密分片, 以 JSON 保存为单独的文件
+type Share struct {
+	Version   int    `json:"version"`
+	Label     string `json:"label"`     // 秘密名称, 如 wallet:用户名 或 masterkey
+	ID        string `json:"id"`        // 拆分标识, 同一次拆分的分片相同
+	Threshold int    `json:"threshold"` // 恢复所需的分片数
+	Total     int    `json:"total"`     // 分片总数
+	Index     int    `json:"index"`     // 分片序号, 1~total
+	Data      string `json:"data"`      // 分片数据, 十六进制
+	Checksum  string `json:"checksum"`  // 分片校验和
+}
+
+func (share *Share) checksum() string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%s:%d:%d:%d:%s", share.Version, share.Label, share.ID, share.Threshold, share.Total, share.Index, share.Data)))
+	return hex.EncodeToString(h[:8])
+}
+
+// Verify 校验分片格式及校验和
+func (share *Share) Verify() error {
+	if share.Version != version {
+		return fmt.Errorf("shamir: share version %d not supported", share.Version)
+	}
+	if share.Threshold < 2 || share.Threshold > share.Total || share.Total > MaxShares {
+		return fmt.Errorf("shamir: invalid threshold %d of %d", share.Threshold, share.Total)
+	}
+	if share.Index < 1 || share.Index > share.Total {
+		return fmt.Errorf("shamir: invalid share index %d", share.Index)
+	}
+	if share.checksum() != share.Checksum {
+		return ErrChecksum
+	}
+	if bts, err := hex.DecodeString(share.Data); err != nil || len(bts) <= digestLen {
+		return fmt.Errorf("shamir: invalid share data")
+	}
+	return nil
+}
+
+// Marshal 分片的 JSON 编码
+func (share *Share) Marshal() ([]byte, error) {
+	return json.MarshalIndent(share, "", "  ")
+}
+
+// ParseShare 解析并校验分片
+func ParseShare(bts []byte) (*Share, error) {
+	share := &Share{}
+	if err := json.Unmarshal(bts, share); err != nil {
+		return nil, err
+	}
+	if err := share.Verify(); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// digest 秘密摘要, 附在秘密之后一起拆分, 不单独保存, 因此少于门限的分片同样不泄露摘要
+func digest(secret []byte) []byte {
+	h := sha256.Sum256(secret)
+	return h[:digestLen]
+}
+
+// Split 将 secret 拆分为 total 个分片, 任意 threshold 个分片可恢复
+func Split(label string, secret []byte, total, threshold int) ([]*Share, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("shamir: empty secret")
+	}
+	if threshold < 2 || threshold > total || total > MaxShares {
+		return nil, fmt.Errorf("shamir: invalid threshold %d of %d", threshold, total)
+	}
+	id := make([]byte, 8)
+	if _, err := io.ReadFull(random, id); err != nil {
+		return nil, err
+	}
+
+	payload := append(append([]byte{}, secret...), digest(secret)...)
+	defer zero(payload)
+	// 每个字节位置一个 threshold-1 次的随机多项式, 常数项为秘密
+	coeffs := make([]byte, threshold)
+	defer zero(coeffs)
+	data := make([][]byte, total)
+	for i := range data {
+		data[i] = make([]byte, len(payload))
+	}
+	for k, b := range payload {
+		coeffs[0] = b
+		if _, err := io.ReadFull(random, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range data {
+			data[i][k] = evaluate(coeffs, byte(i+1))
+		}
+	}
+
+	shares := make([]*Share, total)
+	for i := range shares {
+		shares[i] = &Share{
+			Version:   version,
+			Label:     label,
+			ID:        hex.EncodeToString(id),
+			Threshold: threshold,
+			Total:     total,
+			Index:     i + 1,
+			Data:      hex.EncodeToString(data[i]),
+		}
+		shares[i].Checksum = shares[i].checksum()
+	}
+	return shares, nil
+}
+
+// Combine 由同一次拆分的至少 threshold 个分片恢复秘密, 并校验秘密摘要
+func Combine(shares []*Share) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrNotEnough
+	}
+	first := shares[0]
+	xs := []byte{}
+	ys := [][]byte{}
+	seen := make(map[int]bool)
+	for _, share := range shares {
+		if err := share.Verify(); err != nil {
+			return nil, err
+		}
+		if share.Label != first.Label || share.ID != first.ID || share.Threshold != first.Threshold || share.Total != first.Total || len(share.Data) != len(first.Data) {
+			return nil, fmt.Errorf("shamir: share %d of %s/%s does not belong to %s/%s", share.Index, share.Label, share.ID, first.Label, first.ID)
+		}
+		if seen[share.Index] {
+			continue
+		}
+		seen[share.Index] = true
+		if len(xs) < first.Threshold {
+			data, _ := hex.DecodeString(share.Data)
+			xs = append(xs, byte(share.Index))
+			ys = append(ys, data)
+		}
+	}
+	if len(xs) < first.Threshold {
+		return nil, ErrNotEnough
+	}
+
+	payload := interpolate(xs, ys, 0)
+	defer zero(payload)
+	secret := append([]byte{}, payload[:len(payload)-digestLen]...)
+	if !bytes.Equal(digest(secret), payload[len(payload)-digestLen:]) {
+		zero(secret)
+		return nil, ErrIntegrity
+	}
+	return secret, nil
+}
+
+func zero(bts []byte) {
+	for i := range bts {
+		bts[i] = 0
+	}
+}
@@ -0,0 +1,158 @@
+package shamir
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/erick785/services/common/wallet"
+)
+
+func TestField(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if div(mul(byte(a), byte(b)), byte(b)) != byte(a) {
+				t.Fatalf("%d * %d / %d", a, b, b)
+			}
+		}
+	}
+	// AES 中的例子 {57} * {83} = {c1}
+	if mul(0x57, 0x83) != 0xc1 {
+		t.Fatalf("mul %x", mul(0x57, 0x83))
+	}
+}
+
+// combinations 从 n 个序号中取 k 个的全部组合
+func combinations(n, k int) [][]int {
+	if k == 0 {
+		return [][]int{{}}
+	}
+	result := [][]int{}
+	for i := k - 1; i < n; i++ {
+		for _, c := range combinations(i, k-1) {
+			result = append(result, append(c, i))
+		}
+	}
+	return result
+}
+
+func TestRecoverEntropy(t *testing.T) {
+	for _, tc := range []struct{ total, threshold int }{{2, 2}, {3, 2}, {5, 3}, {6, 6}} {
+		w, err := wallet.NewWallet("test", wallet.NewHexEntropy(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entropy := w.Entropy()
+		shares, err := Split("wallet:test", entropy, tc.total, tc.threshold)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 任意 K 个分片恢复相同的商
+		for _, c := range combinations(tc.total, tc.threshold) {
+			subset := []*Share{}
+			for _, i := range c {
+				bts, _ := shares[i].Marshal()
+				share, err := ParseShare(bts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				subset = append(subset, share)
+			}
+			secret, err := Combine(subset)
+			if err != nil {
+				t.Fatalf("%d of %d %v: %v", tc.threshold, tc.total, c, err)
+			}
+			if hex.EncodeToString(secret) != hex.EncodeToString(w.Entropy()) {
+				t.Fatalf("%d of %d %v recovered %x, expected %s", tc.threshold, tc.total, c, secret, hex.EncodeToString(w.Entropy()))
+			}
+		}
+		// K-1 个分片不能恢复
+		if _, err := Combine(shares[:tc.threshold-1]); err != ErrNotEnough {
+			t.Fatalf("%d of %d with k-1 shares err %v", tc.threshold, tc.total, err)
+		}
+	}
+}
+
+// TestSecrecy K-1 个分片与任意秘密都相容: 对任意候选秘密, 都存在唯一的第 K 个分片使其恢复为该秘密,
+// 即 K-1 个分片不排除任何秘密, 不泄露秘密的任何信息
+func TestSecrecy(t *testing.T) {
+	secret, _ := hex.DecodeString(wallet.NewHexEntropy())
+	shares, err := Split("wallet:test", secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	known := shares[:2]
+	xs := []byte{0}
+	ys := [][]byte{nil}
+	for _, share := range known {
+		data, _ := hex.DecodeString(share.Data)
+		xs = append(xs, byte(share.Index))
+		ys = append(ys, data)
+	}
+	for _, candidate := range [][]byte{secret, make([]byte, len(secret)), bytes.Repeat([]byte{0xff}, len(secret))} {
+		// 过 (0, 候选秘密及摘要) 及已知分片的多项式在 x=3 处的值即为相容的第三个分片
+		ys[0] = append(append([]byte{}, candidate...), digest(candidate)...)
+		forged := *shares[2]
+		forged.Data = hex.EncodeToString(interpolate(xs, ys, byte(forged.Index)))
+		forged.Checksum = forged.checksum()
+		recovered, err := Combine([]*Share{known[0], known[1], &forged})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(recovered, candidate) {
+			t.Fatalf("recovered %x, expected %x", recovered, candidate)
+		}
+	}
+
+	// 单个分片字节在多次拆分中均匀分布, 与秘密无关
+	counts := make([]int, 256)
+	for i := 0; i < 256*64; i++ {
+		shares, _ := Split("", []byte{0x42}, 2, 2)
+		data, _ := hex.DecodeString(shares[0].Data)
+		counts[data[0]]++
+	}
+	for b, count := range counts {
+		if count < 16 || count > 160 {
+			t.Fatalf("share byte %x appeared %d times", b, count)
+		}
+	}
+}
+
+func TestIntegrity(t *testing.T) {
+	secret, _ := hex.DecodeString(wallet.NewHexEntropy())
+	shares, _ := Split("masterkey", secret, 3, 2)
+	others, _ := Split("masterkey", secret, 3, 2)
+
+	// 分片数据被修改
+	tampered := *shares[0]
+	data, _ := hex.DecodeString(tampered.Data)
+	data[0] ^= 1
+	tampered.Data = hex.EncodeToString(data)
+	if _, err := Combine([]*Share{&tampered, shares[1]}); err != ErrChecksum {
+		t.Fatalf("tampered share err %v", err)
+	}
+	// 修改后重算校验和, 由秘密摘要发现
+	tampered.Checksum = tampered.checksum()
+	if _, err := Combine([]*Share{&tampered, shares[1]}); err != ErrIntegrity {
+		t.Fatalf("tampered share with checksum err %v", err)
+	}
+	// 不同拆分的分片
+	if _, err := Combine([]*Share{shares[0], others[1]}); err == nil {
+		t.Fatal("shares of different splits expected error")
+	}
+	// 重复的分片不计数
+	if _, err := Combine([]*Share{shares[0], shares[0]}); err != ErrNotEnough {
+		t.Fatalf("duplicate share err %v", err)
+	}
+	if _, err := ParseShare([]byte(`{"version":1}`)); err == nil {
+		t.Fatal("invalid share expected error")
+	}
+	for _, tc := range []struct{ total, threshold int }{{1, 1}, {2, 3}, {256, 2}} {
+		if _, err := Split("", secret, tc.total, tc.threshold); err == nil {
+			t.Fatalf("split %d of %d expected error", tc.threshold, tc.total)
+		}
+	}
+	if _, err := Split("", nil, 3, 2); err == nil {
+		t.Fatal("empty secret expected error")
+	}
+}
@@ -8,4 +8,11 @@ CREATE TABLE IF NOT EXISTS t_user (
   s_meta longtext NOT NULL comment '其它信息',
   UNIQUE INDEX (s_name)
 );
+CREATE TABLE IF NOT EXISTS t_key (
+  id int(11) NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  s_name char(100) NOT NULL comment '用户标识',
+  s_address char(100) NOT NULL comment '地址',
+  s_key longtext NOT NULL comment '导入的私钥',
+  UNIQUE INDEX (s_name, s_address)
+);
 `
@@ -11,56 +11,107 @@ import (
 	bip39 "github.com/tyler-smith/go-bip39"
 )
 
+// ErrNoEntropy 钱包未解密商(商由签名服务保管), 不能派生私钥
+var ErrNoEntropy = errors.New("wallet entropy not available")
+
+// Wallet 用户钱包; 根私钥不常驻内存, 派生私钥时由商临时计算, 用后清零
 type Wallet struct {
-	Name      string                 //用户名
-	HexEntory string                 //用户商
-	Meta      map[string]interface{} //用户其它信息
+	Name string                 //用户名
+	Meta map[string]interface{} //用户其它信息
 
-	masterKey *hdkeychain.ExtendedKey   //HD root key
-	paths     map[string]DerivationPath //HD path (address -> path)
-	addresses map[uint32]string         //HD address (type -> address)
+	entropy    []byte                    //用户商, 用后以 Zero 清零
+	seedPhrase string                    //BIP39 口令, 只在 WithPassphrase 的结果中设置
+	account    *hdkeychain.ExtendedKey   //观察钱包的账户扩展公钥
+	accountID  uint32                    //观察钱包扩展公钥的账户序号(hardened)
+	paths      map[string]DerivationPath //HD path (address -> path)
+	addresses  map[string]string         //HD address (path -> address)
+	cache      *AddressCache             //跨请求的地址缓存
 	sync.RWMutex
 }
 
 func NewWallet(name string, hexEntory string, meta map[string]interface{}) (*Wallet, error) {
-	wallet := &Wallet{
-		Name:      name,
-		HexEntory: hexEntory,
-		Meta:      meta,
-		paths:     make(map[string]DerivationPath),
-		addresses: make(map[uint32]string),
-	}
-	//商
 	entropy, err := hex.DecodeString(hexEntory)
 	if err != nil {
 		return nil, err
 	}
+	return newWallet(name, entropy, "", meta)
+}
+
+// newWallet 以商创建钱包, 钱包持有 entropy, 失败时清零
+func newWallet(name string, entropy []byte, passphrase string, meta map[string]interface{}) (*Wallet, error) {
+	//校验商, 种子及根私钥在派生时计算
+	if _, err := bip39.NewMnemonic(entropy); err != nil {
+		zero(entropy)
+		return nil, err
+	}
+	return &Wallet{
+		Name:       name,
+		Meta:       meta,
+		entropy:    entropy,
+		seedPhrase: passphrase,
+		paths:      make(map[string]DerivationPath),
+		addresses:  make(map[string]string),
+	}, nil
+}
+
+// Entropy 用户商的副本, 调用方用后清零; 观察钱包返回 nil
+func (wallet *Wallet) Entropy() []byte {
+	if wallet.entropy == nil {
+		return nil
+	}
+	return append([]byte(nil), wallet.entropy...)
+}
+
+// Zero 清零钱包持有的商, 之后不能再派生私钥
+func (wallet *Wallet) Zero() {
+	zero(wallet.entropy)
+}
+
+// masterKey 由商及口令计算根私钥, 调用方用后以 Zero 清零
+func (wallet *Wallet) masterKey() (*hdkeychain.ExtendedKey, error) {
+	if wallet.account != nil {
+		return nil, ErrWatchOnly
+	}
+	if wallet.entropy == nil {
+		return nil, ErrNoEntropy
+	}
 	//助记词
-	mnemonic, err := bip39.NewMnemonic(entropy)
+	mnemonic, err := bip39.NewMnemonic(wallet.entropy)
 	if err != nil {
 		return nil, err
 	}
 	//种子
-	seed := bip39.NewSeed(mnemonic, "")
+	seed := bip39.NewSeed(mnemonic, wallet.seedPhrase)
+	defer zero(seed)
 	//根 key
-	masterKey, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	return hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+}
+
+// deriveKey 派生路径对应的扩展私钥, 中间结果清零, 调用方用后以 Zero 清零
+func (wallet *Wallet) deriveKey(path DerivationPath) (*hdkeychain.ExtendedKey, error) {
+	key, err := wallet.masterKey()
 	if err != nil {
 		return nil, err
 	}
-	wallet.masterKey = masterKey
-	return wallet, nil
-}
-
-// DerivePrivateKey derives the private key of the derivation path.
-func (wallet *Wallet) DerivePrivateKey(path DerivationPath) (*ecdsa.PrivateKey, error) {
-	var err error
-	key := wallet.masterKey
 	for _, n := range path {
-		key, err = key.Child(n)
+		child, err := key.Child(n)
+		key.Zero()
 		if err != nil {
 			return nil, err
 		}
+		key = child
+	}
+	return key, nil
+}
+
+// DerivePrivateKey derives the private key of the derivation path.
+// 调用方用后以 ZeroKey 清零
+func (wallet *Wallet) DerivePrivateKey(path DerivationPath) (*ecdsa.PrivateKey, error) {
+	key, err := wallet.deriveKey(path)
+	if err != nil {
+		return nil, err
 	}
+	defer key.Zero()
 
 	privateKey, err := key.ECPrivKey()
 	if err != nil {
@@ -72,15 +123,36 @@ func (wallet *Wallet) DerivePrivateKey(path DerivationPath) (*ecdsa.PrivateKey,
 
 // DerivePublicKey derives the public key of the derivation path.
 func (wallet *Wallet) DerivePublicKey(path DerivationPath) (*ecdsa.PublicKey, error) {
+	if wallet.account != nil {
+		return wallet.deriveWatchPublicKey(path)
+	}
 	privateKeyECDSA, err := wallet.DerivePrivateKey(path)
 	if err != nil {
 		return nil, err
 	}
+	defer ZeroKey(privateKeyECDSA)
 
-	publicKey := privateKeyECDSA.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
+	publicKeyECDSA := privateKeyECDSA.PublicKey
+	if publicKeyECDSA.X == nil {
 		return nil, errors.New("failed to get public key")
 	}
-	return publicKeyECDSA, nil
+	return &publicKeyECDSA, nil
+}
+
+// ZeroKey 私钥清零
+func ZeroKey(key *ecdsa.PrivateKey) {
+	if key == nil || key.D == nil {
+		return
+	}
+	bits := key.D.Bits()
+	for i := range bits {
+		bits[i] = 0
+	}
+	key.D.SetInt64(0)
+}
+
+func zero(bts []byte) {
+	for i := range bts {
+		bts[i] = 0
+	}
 }
@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -31,3 +32,60 @@ func TestWallet(t *testing.T) {
 	entropy, _ = bip39.NewEntropy(256)
 	fmt.Println(bip39.NewMnemonic(entropy))
 }
+
+func TestAddressIndex(t *testing.T) {
+	w, err := NewWallet("test", NewHexEntropy(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexes := w.AddressIndexes(); len(indexes) != 1 || indexes[0].Account != 0 || indexes[0].Index != 0 {
+		t.Fatalf("default indexes %v", indexes)
+	}
+	if next := w.NextAddressIndex(0); next.Account != 0 || next.Index != 1 {
+		t.Fatalf("next index %v", next)
+	}
+	if next := w.NextAddressIndex(1); next.Account != 1 || next.Index != 0 {
+		t.Fatalf("next account index %v", next)
+	}
+
+	//模拟保存后从数据库读出
+	meta, _ := json.Marshal(w.Meta)
+	ometa := make(map[string]interface{})
+	json.Unmarshal(meta, &ometa)
+	w, err = NewWallet("test", hex.EncodeToString(w.Entropy()), ometa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if indexes := w.AddressIndexes(); len(indexes) != 3 {
+		t.Fatalf("restored indexes %v", indexes)
+	}
+	if next := w.NextAddressIndex(1); next.Account != 1 || next.Index != 1 {
+		t.Fatalf("next restored index %v", next)
+	}
+}
+
+func TestMetaOnly(t *testing.T) {
+	mysql := &Mysql{MetaOnly: true}
+	meta := map[string]interface{}{"k": "v"}
+	w, err := mysql.openWallet("test", "mk2:1:00", meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.WatchOnly() || w.Meta["k"] != "v" || w.Entropy() != nil {
+		t.Fatalf("meta only wallet %v", w)
+	}
+	//没有商, 不能派生私钥、导出助记词或重新加密
+	path, _ := ParseDerivationPath("m/44'/60'/0'/0/0")
+	if _, err := w.DerivePrivateKey(path); err != ErrNoEntropy {
+		t.Fatalf("derive %v", err)
+	}
+	if _, err := w.Mnemonic(); err != ErrNoEntropy {
+		t.Fatalf("mnemonic %v", err)
+	}
+	if _, err := w.WithPassphrase("25th"); err != ErrNoEntropy {
+		t.Fatalf("passphrase %v", err)
+	}
+	if _, err := mysql.sealWallet("test", w); err != ErrNoEntropy {
+		t.Fatalf("seal %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/btcsuite/btcutil/hdkeychain"
+)
+
+// metaXPub 观察钱包的账户扩展公钥在 meta 中的键
+const metaXPub = "xpub"
+
+// ErrWatchOnly 观察钱包没有私钥, 不能签名
+var ErrWatchOnly = errors.New("watch-only wallet")
+
+// NewWatchWallet 由账户级扩展公钥(m/44'/coin'/account')创建观察钱包, 只能派生收款地址, 没有私钥
+func NewWatchWallet(name string, xpub string, meta map[string]interface{}) (*Wallet, error) {
+	account, err := ParseXPub(xpub)
+	if err != nil {
+		return nil, err
+	}
+	accountID, err := xpubChildNum(xpub)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+	meta[metaXPub] = xpub
+	return &Wallet{
+		Name:      name,
+		Meta:      meta,
+		account:   account,
+		accountID: accountID,
+		paths:     make(map[string]DerivationPath),
+		addresses: make(map[string]string),
+	}, nil
+}
+
+// ParseXPub 解析账户级扩展公钥, 拒绝扩展私钥及其它层级的扩展公钥
+func ParseXPub(xpub string) (*hdkeychain.ExtendedKey, error) {
+	key, err := hdkeychain.NewKeyFromString(xpub)
+	if err != nil {
+		return nil, err
+	}
+	if key.IsPrivate() {
+		return nil, errors.New("extended public key required")
+	}
+	if key.Depth() != 3 {
+		return nil, fmt.Errorf("account level extended public key required, depth %d", key.Depth())
+	}
+	if n, err := xpubChildNum(xpub); err != nil {
+		return nil, err
+	} else if n < hdkeychain.HardenedKeyStart {
+		return nil, fmt.Errorf("account level extended public key required, account %d not hardened", n)
+	}
+	return key, nil
+}
+
+// xpubChildNum 扩展公钥的子序号, 账户级扩展公钥即账户序号(hardened);
+// 序列化格式为 版本(4) 深度(1) 父指纹(4) 子序号(4) 链码(32) 公钥(33) 校验(4)
+func xpubChildNum(xpub string) (uint32, error) {
+	bts := base58.Decode(xpub)
+	if len(bts) != 82 {
+		return 0, errors.New("invalid extended key length")
+	}
+	return binary.BigEndian.Uint32(bts[9:13]), nil
+}
+
+// WatchOnly 是否为观察钱包
+func (wallet *Wallet) WatchOnly() bool {
+	return wallet.account != nil
+}
+
+// WatchAccount 观察钱包扩展公钥对应的账户序号, 不含 hardened 标记; 普通钱包为 0
+func (wallet *Wallet) WatchAccount() uint32 {
+	if wallet.account == nil {
+		return 0
+	}
+	return wallet.accountID - hdkeychain.HardenedKeyStart
+}
+
+// XPub 观察钱包的账户扩展公钥, 普通钱包为空
+func (wallet *Wallet) XPub() string {
+	wallet.RLock()
+	defer wallet.RUnlock()
+	if wallet.Meta == nil {
+		return ""
+	}
+	xpub, _ := wallet.Meta[metaXPub].(string)
+	return xpub
+}
+
+// ExtendedPublicKey 派生路径对应的扩展公钥, 如账户路径 m/44'/60'/0' 用于创建观察钱包
+func (wallet *Wallet) ExtendedPublicKey(path DerivationPath) (string, error) {
+	key, err := wallet.deriveKey(path)
+	if err != nil {
+		return "", err
+	}
+	defer key.Zero()
+	pub, err := key.Neuter()
+	if err != nil {
+		return "", err
+	}
+	return pub.String(), nil
+}
+
+// deriveWatchPublicKey 观察钱包由账户扩展公钥派生 change/index, 路径前三级需与扩展公钥的账户一致
+func (wallet *Wallet) deriveWatchPublicKey(path DerivationPath) (*ecdsa.PublicKey, error) {
+	if len(path) != 5 {
+		return nil, fmt.Errorf("watch-only wallet only derives m/44'/coin'/account'/change/index, not %s", path)
+	}
+	//扩展公钥不含用途及币种, 按默认路径校验
+	if path[0] != DefaultBaseDerivationPath[0] || path[1] != DefaultBaseDerivationPath[1] || path[2] != wallet.accountID {
+		return nil, fmt.Errorf("watch-only wallet path %s not under account %d'", path, wallet.WatchAccount())
+	}
+	key := wallet.account
+	for _, n := range path[3:] {
+		if n >= hdkeychain.HardenedKeyStart {
+			return nil, fmt.Errorf("watch-only wallet can not derive hardened path %s", path)
+		}
+		var err error
+		if key, err = key.Child(n); err != nil {
+			return nil, err
+		}
+	}
+	pub, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return pub.ToECDSA(), nil
+}
+
+// ImportWatchWallet 以账户扩展公钥创建观察钱包, 替换已有钱包时保留 meta;
+// 已有观察钱包时 overwrite 为 false 返回 ErrWalletExists; 已有普通钱包时只有 untouched 判断为
+// 未使用(如查询接口自动创建)才替换, 否则返回 ErrWalletExists
+func (mysql *Mysql) ImportWatchWallet(name string, xpub string, overwrite bool, untouched func(old *Wallet) (bool, error)) (*Wallet, error) {
+	old, err := mysql.GetWallet(name)
+	if err != nil {
+		return nil, err
+	}
+	var meta map[string]interface{}
+	if old != nil {
+		if old.WatchOnly() && !overwrite {
+			return nil, ErrWalletExists
+		}
+		if !old.WatchOnly() {
+			if ok, err := untouched(old); err != nil {
+				return nil, err
+			} else if !ok {
+				return nil, ErrWalletExists
+			}
+		}
+		meta = old.Meta
+	}
+	wallet, err := NewWatchWallet(name, xpub, meta)
+	if err != nil {
+		return nil, err
+	}
+	if err := mysql.UpdateWallet(wallet); err != nil {
+		return nil, err
+	}
+	mysql.Cache.Remove(name)
+	wallet.cache = mysql.Cache
+	return wallet, nil
+}
@@ -0,0 +1,112 @@
+package wallet
+
+import (
+	"testing"
+)
+
+func TestWatchWallet(t *testing.T) {
+	w, err := NewWallet("test", NewHexEntropy(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, _ := ParseDerivationPath("m/44'/60'/0'")
+	xpub, err := w.ExtendedPublicKey(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watch, err := NewWatchWallet("watch", xpub, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !watch.WatchOnly() || w.WatchOnly() || watch.XPub() != xpub {
+		t.Fatalf("watch only %v %v %s", watch.WatchOnly(), w.WatchOnly(), watch.XPub())
+	}
+
+	//观察钱包派生的收款地址与原钱包一致
+	for _, str := range []string{"m/44'/60'/0'/0/0", "m/44'/60'/0'/0/7", "m/44'/60'/0'/1/2"} {
+		path, _ := ParseDerivationPath(str)
+		expected, _ := w.DerivePublicKey(path)
+		pub, err := watch.DerivePublicKey(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub.X.Cmp(expected.X) != 0 || pub.Y.Cmp(expected.Y) != 0 {
+			t.Fatalf("%s public key mismatch", str)
+		}
+	}
+
+	path, _ := ParseDerivationPath("m/44'/60'/0'/0/0")
+	if _, err := watch.DerivePrivateKey(path); err != ErrWatchOnly {
+		t.Fatalf("derive private key err %v", err)
+	}
+	if _, err := watch.Mnemonic(); err != ErrWatchOnly {
+		t.Fatalf("mnemonic err %v", err)
+	}
+	if _, err := watch.WithPassphrase("25th word"); err != ErrWatchOnly {
+		t.Fatalf("passphrase err %v", err)
+	}
+	hardened, _ := ParseDerivationPath("m/44'/60'/0'/0'/0")
+	if _, err := watch.DerivePublicKey(hardened); err == nil {
+		t.Fatal("hardened path expected error")
+	}
+
+	//只接受账户级扩展公钥
+	root, _ := w.ExtendedPublicKey(DerivationPath{})
+	master, err := w.masterKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer master.Zero()
+	for _, str := range []string{"", "xpub", root, master.String()} {
+		if _, err := NewWatchWallet("watch", str, nil); err == nil {
+			t.Errorf("NewWatchWallet(%q) expected error", str)
+		}
+	}
+}
+
+func TestWatchWalletAccount(t *testing.T) {
+	w, err := NewWallet("test", NewHexEntropy(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, _ := ParseDerivationPath("m/44'/60'/3'")
+	xpub, err := w.ExtendedPublicKey(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	watch, err := NewWatchWallet("watch", xpub, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watch.WatchAccount() != 3 || w.WatchAccount() != 0 {
+		t.Fatalf("watch account %d %d", watch.WatchAccount(), w.WatchAccount())
+	}
+
+	path, _ := ParseDerivationPath("m/44'/60'/3'/0/5")
+	expected, _ := w.DerivePublicKey(path)
+	pub, err := watch.DerivePublicKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub.X.Cmp(expected.X) != 0 || pub.Y.Cmp(expected.Y) != 0 {
+		t.Fatalf("%s public key mismatch", path)
+	}
+
+	//路径前三级需与扩展公钥的账户一致
+	for _, str := range []string{"m/44'/60'/0'/0/5", "m/44'/61'/3'/0/5", "m/49'/60'/3'/0/5"} {
+		path, _ := ParseDerivationPath(str)
+		if _, err := watch.DerivePublicKey(path); err == nil {
+			t.Errorf("%s expected error", str)
+		}
+	}
+
+	//账户层级需为 hardened
+	unhardened, _ := ParseDerivationPath("m/44'/60'/3")
+	xpub, err = w.ExtendedPublicKey(unhardened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewWatchWallet("watch", xpub, nil); err == nil {
+		t.Error("unhardened account expected error")
+	}
+}
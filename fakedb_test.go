@@ -0,0 +1,145 @@
+package main
+
+import (
+	"container/list"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeQuery 测试数据库按 SQL 返回的列及行, 写入语句返回空结果
+type fakeQuery func(query string) ([]string, [][]driver.Value, error)
+
+// fakeDriver 测试用 database/sql 驱动, 查询及写入均交给 fakeQuery
+type fakeDriver struct {
+	query fakeQuery
+}
+
+var fakeDrivers struct {
+	sync.Mutex
+	count int
+}
+
+// newFakeMysql 以 fakeQuery 作为数据库的 Mysql, 内存区块为空, rpc 为节点 RPC 地址
+func newFakeMysql(t *testing.T, query fakeQuery, rpc string) *Mysql {
+	fakeDrivers.Lock()
+	fakeDrivers.count++
+	name := fmt.Sprintf("fake%d", fakeDrivers.count)
+	fakeDrivers.Unlock()
+	sql.Register(name, &fakeDriver{query: query})
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Mysql{
+		db:        db,
+		RPC:       &RPCClient{RPCHost: rpc},
+		memBlocks: list.New(),
+	}
+}
+
+// newFakeRPC 节点 RPC, 全部方法返回 result
+func newFakeRPC(result string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":%q}`, result)
+	}))
+}
+
+// newFakeRPCMethods 节点 RPC, 按方法返回 json 格式的 result, 未列出的方法返回 null
+func newFakeRPCMethods(results map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Method string `json:"method"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&req)
+		result, ok := results[req.Method]
+		if !ok {
+			result = "null"
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"result":%s}`, result)
+	}))
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{query: d.query}, nil
+}
+
+type fakeConn struct {
+	query fakeQuery
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{query: c.query, sql: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	return nil
+}
+
+type fakeStmt struct {
+	query fakeQuery
+	sql   string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if _, _, err := s.query(s.sql); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	columns, rows, err := s.query(s.sql)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: columns, rows: rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
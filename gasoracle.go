@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 燃料单价档位
+const (
+	SpeedSlow     = "slow"
+	SpeedStandard = "standard"
+	SpeedFast     = "fast"
+)
+
+var speeds = []string{SpeedSlow, SpeedStandard, SpeedFast}
+
+// GasPrices 各档位燃料单价
+type GasPrices struct {
+	Slow     *big.Int `json:"slow"`     // 慢
+	Standard *big.Int `json:"standard"` // 标准
+	Fast     *big.Int `json:"fast"`     // 快
+	Suggest  *big.Int `json:"suggest"`  // 节点建议价格
+	Samples  int      `json:"samples"`  // 采样交易数, 0 时各档位为节点建议价格
+	Time     int64    `json:"time"`     // 计算时间
+}
+
+// Price 档位对应的燃料单价, 为空取标准档
+func (prices *GasPrices) Price(speed string) (*big.Int, error) {
+	switch speed {
+	case SpeedSlow:
+		return new(big.Int).Set(prices.Slow), nil
+	case "", SpeedStandard:
+		return new(big.Int).Set(prices.Standard), nil
+	case SpeedFast:
+		return new(big.Int).Set(prices.Fast), nil
+	}
+	return nil, fmt.Errorf("unknown speed %s", speed)
+}
+
+// blockSample 区块内交易的燃料单价
+type blockSample struct {
+	height int64
+	prices []*big.Int
+}
+
+// GasOracle 燃料单价预言机: 采样扫描到的最近区块及内存池交易的燃料单价, 按分位数计算各档位价格,
+// 没有采样时使用节点建议价格, 结果缓存一段时间
+type GasOracle struct {
+	db *Mysql
+
+	Blocks      int           // 采样区块数
+	Percentiles []int         // 慢/标准/快档位的分位数
+	TTL         time.Duration // 缓存时间
+
+	blocks  []*blockSample
+	pending []*big.Int
+	cache   *GasPrices
+	sync.Mutex
+}
+
+// NewGasOracle 创建燃料单价预言机
+func NewGasOracle(db *Mysql, blocks int, percentiles []int, ttl time.Duration) *GasOracle {
+	return &GasOracle{
+		db:          db,
+		Blocks:      blocks,
+		Percentiles: percentiles,
+		TTL:         ttl,
+	}
+}
+
+// ParsePercentiles 解析档位分位数, 如 30,60,90
+func ParsePercentiles(str string) ([]int, error) {
+	parts := strings.Split(str, ",")
+	if len(parts) != len(speeds) {
+		return nil, fmt.Errorf("expected %d percentiles, found %d: %s", len(speeds), len(parts), str)
+	}
+	percentiles := []int{}
+	for i, part := range parts {
+		p, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile %s", part)
+		}
+		if i > 0 && p < percentiles[i-1] {
+			return nil, fmt.Errorf("percentiles not ascending: %s", str)
+		}
+		percentiles = append(percentiles, p)
+	}
+	return percentiles, nil
+}
+
+// AddBlock 采样新扫描的区块, 回滚后重新扫描的同高度区块替换原采样
+func (oracle *GasOracle) AddBlock(blk *Block) {
+	sample := &blockSample{
+		height: blk.Height,
+	}
+	for _, tx := range blk.Transactions {
+		if tx.GasPrice != nil && tx.GasPrice.Sign() > 0 {
+			sample.prices = append(sample.prices, tx.GasPrice)
+		}
+	}
+
+	oracle.Lock()
+	defer oracle.Unlock()
+	blocks := []*blockSample{}
+	for _, b := range oracle.blocks {
+		if b.height < blk.Height {
+			blocks = append(blocks, b)
+		}
+	}
+	blocks = append(blocks, sample)
+	if len(blocks) > oracle.Blocks {
+		blocks = blocks[len(blocks)-oracle.Blocks:]
+	}
+	oracle.blocks = blocks
+}
+
+// SetPending 采样当前内存池交易
+func (oracle *GasOracle) SetPending(txs []*Transaction) {
+	pending := []*big.Int{}
+	for _, tx := range txs {
+		if tx.GasPrice != nil && tx.GasPrice.Sign() > 0 {
+			pending = append(pending, tx.GasPrice)
+		}
+	}
+
+	oracle.Lock()
+	defer oracle.Unlock()
+	oracle.pending = pending
+}
+
+// Prices 各档位燃料单价, 缓存未过期时直接返回
+func (oracle *GasOracle) Prices() (*GasPrices, error) {
+	oracle.Lock()
+	defer oracle.Unlock()
+	if oracle.cache != nil && time.Now().Sub(time.Unix(oracle.cache.Time, 0)) < oracle.TTL {
+		return oracle.cache, nil
+	}
+
+	samples := append([]*big.Int{}, oracle.pending...)
+	for _, b := range oracle.blocks {
+		samples = append(samples, b.prices...)
+	}
+	suggest, err := oracle.db.GetGasPrice()
+	if err != nil && len(samples) == 0 {
+		return nil, err
+	}
+	if suggest != nil {
+		suggest = truncGwei(suggest)
+	}
+
+	prices := &GasPrices{
+		Slow:     suggest,
+		Standard: suggest,
+		Fast:     suggest,
+		Suggest:  suggest,
+		Samples:  len(samples),
+		Time:     time.Now().Unix(),
+	}
+	if len(samples) > 0 {
+		sort.Slice(samples, func(i, j int) bool {
+			return samples[i].Cmp(samples[j]) < 0
+		})
+		percentile := func(p int) *big.Int {
+			return truncGwei(samples[(len(samples)-1)*p/100])
+		}
+		prices.Slow = percentile(oracle.Percentiles[0])
+		prices.Standard = percentile(oracle.Percentiles[1])
+		prices.Fast = percentile(oracle.Percentiles[2])
+	}
+	oracle.cache = prices
+	return prices, nil
+}
+
+// GasPrice 档位对应的燃料单价, 为空取标准档
+func (oracle *GasOracle) GasPrice(speed string) (*big.Int, error) {
+	prices, err := oracle.Prices()
+	if err != nil {
+		return nil, err
+	}
+	return prices.Price(speed)
+}
+
+// truncGwei 燃料单价取整到 gwei, 不足 1 gwei 时保持不变
+func truncGwei(gasprice *big.Int) *big.Int {
+	gwei := big.NewInt(1e9)
+	if gasprice.Cmp(gwei) < 0 {
+		return new(big.Int).Set(gasprice)
+	}
+	return new(big.Int).Sub(gasprice, new(big.Int).Mod(gasprice, gwei))
+}
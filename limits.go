@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erick785/services/common/wallet"
+)
+
+// 钱包 meta 中的键
+const (
+	metaLimits  = "limits"  // 用户转出规则
+	metaRenamed = "renamed" // 最近修改手机号时间
+)
+
+// 限额规则名称
+const (
+	ruleMaxTx    = "max_tx"
+	ruleDaily    = "daily"
+	ruleMonthly  = "monthly"
+	ruleMaxBatch = "max_batch"
+	ruleCooldown = "cooldown"
+)
+
+// Limit 单个币种的转出限额, 为空不限制
+type Limit struct {
+	MaxTx   *big.Int `json:"max_tx"`  // 单笔上限
+	Daily   *big.Int `json:"daily"`   // 24 小时累计上限
+	Monthly *big.Int `json:"monthly"` // 30 天累计上限
+}
+
+// LimitRules 转出规则
+type LimitRules struct {
+	Tokens   map[string]*Limit `json:"tokens"`    // token 地址 -> 限额, "" 为原生币
+	MaxBatch int               `json:"max_batch"` // 单次批量订单数上限, 0 不限制
+	Cooldown int64             `json:"cooldown"`  // 修改手机号后禁止转出的秒数, 0 不限制
+}
+
+// LimitError 触发的限额规则
+type LimitError struct {
+	Rule  string
+	Limit interface{}
+	Value interface{}
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("limit %s exceeded: %v > %v", e.Rule, e.Value, e.Limit)
+}
+
+// LimitChecker 签名前检查转出规则, 默认规则可被钱包 meta 中的用户规则覆盖
+type LimitChecker struct {
+	db       *Mysql
+	Defaults *LimitRules
+	locks    map[string]*sync.Mutex
+	sync.Mutex
+
+	AddressDelay time.Duration // 常用地址冷却期
+}
+
+// NewLimitChecker 创建规则检查器
+func NewLimitChecker(db *Mysql, rules *LimitRules) *LimitChecker {
+	if rules == nil {
+		rules = &LimitRules{}
+	}
+	return &LimitChecker{
+		db:       db,
+		Defaults: rules,
+		locks:    make(map[string]*sync.Mutex),
+	}
+}
+
+func (checker *LimitChecker) lock(phone string) *sync.Mutex {
+	checker.Mutex.Lock()
+	defer checker.Mutex.Unlock()
+	l, ok := checker.locks[phone]
+	if !ok {
+		l = &sync.Mutex{}
+		checker.locks[phone] = l
+	}
+	return l
+}
+
+// Lock 锁定用户, 同一用户的累计限额检查与订单保存串行执行, 需在地址锁之后获取
+func (checker *LimitChecker) Lock(phone string) {
+	checker.lock(phone).Lock()
+}
+
+// Unlock 解锁用户
+func (checker *LimitChecker) Unlock(phone string) {
+	checker.lock(phone).Unlock()
+}
+
+// ParseLimitRules 解析 json 格式的规则
+func ParseLimitRules(str string) (*LimitRules, error) {
+	rules := &LimitRules{}
+	if len(str) == 0 {
+		return rules, nil
+	}
+	if err := json.Unmarshal([]byte(str), rules); err != nil {
+		return nil, err
+	}
+	return rules.normalize(), nil
+}
+
+func (rules *LimitRules) normalize() *LimitRules {
+	tokens := make(map[string]*Limit)
+	for token, limit := range rules.Tokens {
+		tokens[strings.ToLower(token)] = limit
+	}
+	rules.Tokens = tokens
+	return rules
+}
+
+// metaValue 读取 meta 中的值, meta 从数据库读出后为通用类型, 通过 json 转换
+func metaValue(wlt *wallet.Wallet, key string, v interface{}) bool {
+	if wlt.Meta == nil || wlt.Meta[key] == nil {
+		return false
+	}
+	bts, err := json.Marshal(wlt.Meta[key])
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(bts, v) == nil
+}
+
+// UserRules 用户规则覆盖, 未设置返回 nil
+func UserRules(wlt *wallet.Wallet) *LimitRules {
+	rules := &LimitRules{}
+	if !metaValue(wlt, metaLimits, rules) {
+		return nil
+	}
+	return rules.normalize()
+}
+
+// SetUserRules 设置用户规则覆盖, rules 为 nil 时清除, 由调用方保存钱包
+func SetUserRules(wlt *wallet.Wallet, rules *LimitRules) {
+	if wlt.Meta == nil {
+		wlt.Meta = make(map[string]interface{})
+	}
+	if rules == nil {
+		delete(wlt.Meta, metaLimits)
+		return
+	}
+	wlt.Meta[metaLimits] = rules.normalize()
+}
+
+// SetRenamed 记录修改手机号时间, 由调用方保存钱包
+func SetRenamed(wlt *wallet.Wallet, t time.Time) {
+	if wlt.Meta == nil {
+		wlt.Meta = make(map[string]interface{})
+	}
+	wlt.Meta[metaRenamed] = t.Unix()
+}
+
+// Rules 用户生效的规则: 用户规则中非零的项覆盖默认规则, 币种限额整体覆盖
+func (checker *LimitChecker) Rules(wlt *wallet.Wallet) *LimitRules {
+	rules := &LimitRules{
+		Tokens:   make(map[string]*Limit),
+		MaxBatch: checker.Defaults.MaxBatch,
+		Cooldown: checker.Defaults.Cooldown,
+	}
+	for token, limit := range checker.Defaults.Tokens {
+		rules.Tokens[token] = limit
+	}
+	user := UserRules(wlt)
+	if user == nil {
+		return rules
+	}
+	for token, limit := range user.Tokens {
+		rules.Tokens[token] = limit
+	}
+	if user.MaxBatch > 0 {
+		rules.MaxBatch = user.MaxBatch
+	}
+	if user.Cooldown > 0 {
+		rules.Cooldown = user.Cooldown
+	}
+	return rules
+}
+
+// CheckAccount 检查批量订单数及修改手机号后的冷却期
+func (checker *LimitChecker) CheckAccount(rules *LimitRules, wlt *wallet.Wallet, count int) error {
+	if rules.MaxBatch > 0 && count > rules.MaxBatch {
+		return &LimitError{Rule: ruleMaxBatch, Limit: rules.MaxBatch, Value: count}
+	}
+	var renamed int64
+	if rules.Cooldown > 0 && metaValue(wlt, metaRenamed, &renamed) {
+		if elapsed := time.Now().Unix() - renamed; elapsed < rules.Cooldown {
+			return &LimitError{Rule: ruleCooldown, Limit: fmt.Sprintf("%ds", rules.Cooldown), Value: fmt.Sprintf("%ds", elapsed)}
+		}
+	}
+	return nil
+}
+
+// LimitUsage 用户某币种的额度使用情况, 批量订单逐笔累计
+type LimitUsage struct {
+	limit   *Limit
+	daily   *big.Int
+	monthly *big.Int
+}
+
+// Usage 查询用户全部地址某币种 24 小时及 30 天内已转出金额
+func (checker *LimitChecker) Usage(rules *LimitRules, phone string, token string) (*LimitUsage, error) {
+	usage := &LimitUsage{
+		limit:   rules.Tokens[strings.ToLower(token)],
+		daily:   big.NewInt(0),
+		monthly: big.NewInt(0),
+	}
+	if usage.limit == nil || (usage.limit.Daily == nil && usage.limit.Monthly == nil) {
+		return usage, nil
+	}
+	now := time.Now()
+	var err error
+	if usage.daily, err = checker.db.GetOrderSum(phone, token, now.Add(-24*time.Hour).Unix()); err != nil {
+		return nil, err
+	}
+	if usage.monthly, err = checker.db.GetOrderSum(phone, token, now.Add(-30*24*time.Hour).Unix()); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// Use 检查并累计一笔转出
+func (usage *LimitUsage) Use(value *big.Int) error {
+	if usage.limit == nil {
+		return nil
+	}
+	daily := new(big.Int).Add(usage.daily, value)
+	monthly := new(big.Int).Add(usage.monthly, value)
+	if usage.limit.MaxTx != nil && value.Cmp(usage.limit.MaxTx) > 0 {
+		return &LimitError{Rule: ruleMaxTx, Limit: usage.limit.MaxTx, Value: value}
+	}
+	if usage.limit.Daily != nil && daily.Cmp(usage.limit.Daily) > 0 {
+		return &LimitError{Rule: ruleDaily, Limit: usage.limit.Daily, Value: daily}
+	}
+	if usage.limit.Monthly != nil && monthly.Cmp(usage.limit.Monthly) > 0 {
+		return &LimitError{Rule: ruleMonthly, Limit: usage.limit.Monthly, Value: monthly}
+	}
+	usage.daily, usage.monthly = daily, monthly
+	return nil
+}
+
+// OrderGuard 签名前检查订单的收款地址及转出限额, 同一次持有用户锁期间的订单逐笔累计
+type OrderGuard struct {
+	checker *LimitChecker
+	wlt     *wallet.Wallet
+	rules   *LimitRules
+	usages  map[string]*LimitUsage
+}
+
+// Guard 按钱包规则创建订单检查, 调用方需在持有地址锁及用户锁期间使用
+func (checker *LimitChecker) Guard(wlt *wallet.Wallet, rules *LimitRules) *OrderGuard {
+	return &OrderGuard{
+		checker: checker,
+		wlt:     wlt,
+		rules:   rules,
+		usages:  make(map[string]*LimitUsage),
+	}
+}
+
+// Check 检查收款地址, 并检查累计订单转出金额, guard 为 nil 时不检查
+func (guard *OrderGuard) Check(orderInfo *OrderInfo) error {
+	if guard == nil {
+		return nil
+	}
+	//全部订单检查收款地址或调用的合约: 不附带金额的合约调用(如 approve、transferFrom)同样可以转移资产
+	if err := CheckDestination(guard.wlt, orderInfo.To, guard.checker.AddressDelay); err != nil {
+		return err
+	}
+	//累计额度按用户统计, 不区分转出地址
+	token := strings.ToLower(orderInfo.TokenAddress)
+	usage, ok := guard.usages[token]
+	if !ok {
+		var err error
+		if usage, err = guard.checker.Usage(guard.rules, guard.wlt.Name, token); err != nil {
+			return err
+		}
+		guard.usages[token] = usage
+	}
+	return usage.Use(orderInfo.Value)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"database/sql/driver"
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/erick785/services/common/wallet"
+)
+
+var sumRegex = regexp.MustCompile(`s_phone='([^']*)' and s_token='([^']*)'`)
+
+func newTestWallet(t *testing.T, name string) *wallet.Wallet {
+	wlt, err := wallet.NewWallet(name, wallet.NewHexEntropy(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wlt
+}
+
+// limitRule 错误触发的规则, 未触发为空
+func limitRule(err error) string {
+	switch e := err.(type) {
+	case nil:
+		return ""
+	case *LimitError:
+		return e.Rule
+	case *DestinationError:
+		return "destination"
+	}
+	return err.Error()
+}
+
+func TestCheckAccount(t *testing.T) {
+	checker := NewLimitChecker(nil, nil)
+	for _, tc := range []struct {
+		name    string
+		rules   *LimitRules
+		renamed time.Duration // 距修改手机号的时间, 0 为未修改
+		count   int
+		want    string
+	}{
+		{"no rules", &LimitRules{}, time.Second, 100, ""},
+		{"batch", &LimitRules{MaxBatch: 2}, 0, 2, ""},
+		{"batch exceeded", &LimitRules{MaxBatch: 2}, 0, 3, ruleMaxBatch},
+		{"never renamed", &LimitRules{Cooldown: 3600}, 0, 1, ""},
+		{"cooldown", &LimitRules{Cooldown: 3600}, time.Minute, 1, ruleCooldown},
+		{"cooldown passed", &LimitRules{Cooldown: 3600}, 2 * time.Hour, 1, ""},
+	} {
+		wlt := newTestWallet(t, "user")
+		if tc.renamed > 0 {
+			SetRenamed(wlt, time.Now().Add(-tc.renamed))
+		}
+		if got := limitRule(checker.CheckAccount(tc.rules, wlt, tc.count)); got != tc.want {
+			t.Errorf("%s: got %q, expected %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestOrderGuard(t *testing.T) {
+	token := "0x00000000000000000000000000000000000000cc"
+	to := "0x00000000000000000000000000000000000000bb"
+	other := "0x00000000000000000000000000000000000000dd"
+	order := func(from string, tokenAddress string, recipient string, value int64, data string) *OrderInfo {
+		return &OrderInfo{From: from, TokenAddress: tokenAddress, To: recipient, Value: big.NewInt(value), Data: data}
+	}
+	limit := func(maxTx, daily, monthly int64) *Limit {
+		l := &Limit{}
+		if maxTx > 0 {
+			l.MaxTx = big.NewInt(maxTx)
+		}
+		if daily > 0 {
+			l.Daily = big.NewInt(daily)
+		}
+		if monthly > 0 {
+			l.Monthly = big.NewInt(monthly)
+		}
+		return l
+	}
+	for _, tc := range []struct {
+		name   string
+		tokens map[string]*Limit
+		used   map[string]string // 用户/token -> 已转出金额
+		setup  func(wlt *wallet.Wallet)
+		orders []*OrderInfo
+		want   []string
+	}{
+		{
+			name:   "no limit",
+			orders: []*OrderInfo{order("0x01", "", to, 1000, "")},
+			want:   []string{""},
+		},
+		{
+			name:   "max tx",
+			tokens: map[string]*Limit{"": limit(10, 0, 0)},
+			orders: []*OrderInfo{order("0x01", "", to, 10, ""), order("0x01", "", to, 11, "")},
+			want:   []string{"", ruleMaxTx},
+		},
+		{
+			//同一用户不同转出地址的订单合并累计
+			name:   "daily across addresses",
+			tokens: map[string]*Limit{"": limit(0, 100, 0)},
+			used:   map[string]string{"user/": "90"},
+			orders: []*OrderInfo{order("0x01", "", to, 5, ""), order("0x02", "", to, 6, ""), order("0x02", "", to, 5, "")},
+			want:   []string{"", ruleDaily, ""},
+		},
+		{
+			name:   "other user usage",
+			tokens: map[string]*Limit{"": limit(0, 100, 0)},
+			used:   map[string]string{"other/": "100"},
+			orders: []*OrderInfo{order("0x01", "", to, 100, "")},
+			want:   []string{""},
+		},
+		{
+			name:   "monthly",
+			tokens: map[string]*Limit{"": limit(0, 0, 50)},
+			used:   map[string]string{"user/": "50"},
+			orders: []*OrderInfo{order("0x01", "", to, 1, "")},
+			want:   []string{ruleMonthly},
+		},
+		{
+			name:   "token limit",
+			tokens: map[string]*Limit{token: limit(0, 100, 0)},
+			used:   map[string]string{"user/" + token: "100", "user/": "1000"},
+			orders: []*OrderInfo{order("0x01", "", to, 1000, ""), order("0x01", token, to, 1, "")},
+			want:   []string{"", ruleDaily},
+		},
+		{
+			name:   "cooling-off",
+			setup:  func(wlt *wallet.Wallet) { AddBookAddress(wlt, to, "") },
+			orders: []*OrderInfo{order("0x01", "", to, 1, ""), order("0x01", "", other, 1, "")},
+			want:   []string{"destination", ""},
+		},
+		{
+			name: "whitelist only",
+			setup: func(wlt *wallet.Wallet) {
+				AddBookAddress(wlt, to, "").Time = time.Now().Add(-2 * time.Hour).Unix()
+				SetWhitelistOnly(wlt, true, time.Hour)
+			},
+			orders: []*OrderInfo{order("0x01", "", to, 1, ""), order("0x01", "", other, 1, ""), order("0x01", token, other, 1, "")},
+			want:   []string{"", "destination", "destination"},
+		},
+		{
+			//不附带金额的合约调用(如 approve)检查被调用的合约
+			name: "whitelist only contract call",
+			setup: func(wlt *wallet.Wallet) {
+				AddBookAddress(wlt, to, "").Time = time.Now().Add(-2 * time.Hour).Unix()
+				SetWhitelistOnly(wlt, true, time.Hour)
+			},
+			orders: []*OrderInfo{order("0x01", "", to, 0, "0x095ea7b3"), order("0x01", "", other, 0, "0x095ea7b3"), order("0x01", "", "", 0, "0x60")},
+			want:   []string{"", "destination", "destination"},
+		},
+	} {
+		db := newFakeMysql(t, func(query string) ([]string, [][]driver.Value, error) {
+			sum := "0"
+			if m := sumRegex.FindStringSubmatch(query); m != nil && len(tc.used[m[1]+"/"+m[2]]) > 0 {
+				sum = tc.used[m[1]+"/"+m[2]]
+			}
+			return []string{"sum"}, [][]driver.Value{{sum}}, nil
+		}, "")
+		checker := NewLimitChecker(db, &LimitRules{Tokens: tc.tokens})
+		checker.AddressDelay = time.Hour
+		wlt := newTestWallet(t, "user")
+		if tc.setup != nil {
+			tc.setup(wlt)
+		}
+		guard := checker.Guard(wlt, checker.Rules(wlt))
+		for i, orderInfo := range tc.orders {
+			if got := limitRule(guard.Check(orderInfo)); got != tc.want[i] {
+				t.Errorf("%s: order %d got %q, expected %q", tc.name, i, got, tc.want[i])
+			}
+		}
+	}
+
+	var guard *OrderGuard
+	if err := guard.Check(order("0x01", "", to, 1, "")); err != nil {
+		t.Errorf("nil guard: %v", err)
+	}
+}
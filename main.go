@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -9,11 +10,15 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/erick785/services/common"
+	"github.com/erick785/services/common/abi"
 	"github.com/erick785/services/common/log"
+	"github.com/erick785/services/common/signer"
 	"github.com/erick785/services/common/sms"
+	"github.com/erick785/services/common/unit"
 	"github.com/erick785/services/common/wallet"
 	gin "gopkg.in/gin-gonic/gin.v1"
 )
@@ -36,6 +41,50 @@ func main() {
 	rpcuser := flag.String("rpcuser", "", "rpc user")
 	rpcpassword := flag.String("rpcpassword", "", "rpc password")
 
+	// 燃料估算安全余量(百分比)
+	gasmargin := flag.Int64("gasmargin", 20, "gas estimate safety margin, percent")
+
+	// 未打包交易替换
+	bumptimeout := flag.Int64("bumptimeout", 0, "replace unmined tx with higher gas price after seconds, 0 disable")
+	bumppercent := flag.Int64("bumppercent", 10, "gas price bump, percent")
+
+	// 离线签名预留序号
+	reservetimeout := flag.Int64("reservetimeout", 86400, "seconds a buildtx nonce stays reserved for offline signing")
+	gasblocks := flag.Int("gasblocks", 20, "gas price oracle sample recent blocks")
+	gaspercentiles := flag.String("gaspercentiles", "30,60,90", "gas price oracle slow,standard,fast percentiles")
+	gascache := flag.Int64("gascache", 10, "gas price oracle cache seconds")
+
+	// 归集
+	sweepaddress := flag.String("sweepaddress", "", "sweep collection address, empty disable")
+	sweepthreshold := flag.String("sweepthreshold", "1000000000000000000", "sweep addresses with balance above threshold")
+	sweepinterval := flag.Int64("sweepinterval", 0, "sweep interval seconds, 0 manual only")
+
+	// 管理接口令牌
+	admintoken := flag.String("admintoken", "", "admin token for admin endpoints, empty disable")
+	flag.StringVar(admintoken, "sweeptoken", "", "deprecated alias of -admintoken")
+
+	// 转出规则
+	limitrules := flag.String("limits", "", `withdrawal rules json, e.g. {"tokens":{"":{"max_tx":1000000000000000000,"daily":5000000000000000000}},"max_batch":20,"cooldown":86400}`)
+
+	// 常用地址冷却期
+	addressdelay := flag.Int64("addressdelay", 86400, "seconds before a new address book entry can receive funds")
+
+	// 钱包主密钥: 密钥文件优先, 否则读取环境变量 WALLET_MASTER_KEYS
+	masterkeyfile := flag.String("masterkeyfile", "", "wallet master key file, lines of version:hexkey, highest version encrypts")
+	rotatekeys := flag.Bool("rotatekeys", false, "re-encrypt all wallets under the current master key and exit")
+	insecurelegacy := flag.Bool("insecurelegacy", false, "allow running without a master key, wallets stay in the legacy user-keyed format")
+
+	// 地址缓存: 按用户缓存已派生的地址, 避免每个请求重新派生
+	addresscache := flag.Int("addresscache", 10000, "wallets whose derived addresses are cached, 0 disable")
+
+	// 签名服务: 为空时在进程内签名, 否则主密钥、商及私钥只存在于签名服务
+	signerurl := flag.String("signer", "", "remote signer url, e.g. http://127.0.0.1:8090, empty sign in process")
+	signertoken := flag.String("signertoken", "", "remote signer access token")
+	signertimeout := flag.Int64("signertimeout", 10, "remote signer request timeout seconds")
+
+	// 助记词导出次数限制(每 24 小时), 0 禁止导出
+	exportlimit := flag.Int("exportlimit", 3, "mnemonic exports allowed per user per 24 hours, 0 disable")
+
 	// white list
 	whitelist := strings.Split(*flag.String("whitelist", "", "white list"), ",")
 
@@ -54,6 +103,33 @@ func main() {
 		return false
 	}
 
+	// 管理令牌校验
+	isAdmin := func(token string) bool {
+		return len(*admintoken) > 0 && strings.Compare(*admintoken, token) == 0
+	}
+
+	// 交易验证码校验, skiplist 中的用户跳过
+	verifyCode := func(c *gin.Context, tag string, phone string, code string) int {
+		skip := inlist(skiplist, phone)
+		if err := sms.VailMobile(phone); err != nil {
+			log.Errorf("[%s] %v VailMobile err %v", tag, phone, err)
+			return codePhoneValidate
+		}
+		if skip {
+			return codeOk
+		}
+		if err := sms.VailCode(code); err != nil {
+			log.Errorf("[%s] %v VailCode err %v", tag, phone, err)
+			return codeSMSValidate
+		}
+		if token, ok := getsessions(c).Get(phone).(*Token); !ok || strings.Compare(token.SendTxCode, code) != 0 {
+			return codeSMSValidate
+		} else if time.Now().Sub(time.Unix(token.SendTxTime, 0)) > 600*time.Second {
+			return codeSMSExpire
+		}
+		return codeOk
+	}
+
 	// coindb
 	db := &Mysql{
 		DBName: *dbname,
@@ -71,31 +147,104 @@ func main() {
 	}
 	defer db.Close()
 
-	// Wallet
+	// Nonce
+	nonces := NewNonceManager(db, db.RPC)
+	nonces.ReserveTimeout = time.Duration(*reservetimeout) * time.Second
+
+	// Limits
+	rules, err := ParseLimitRules(*limitrules)
+	if err != nil {
+		panic(err)
+	}
+	limits := NewLimitChecker(db, rules)
+	limits.AddressDelay = time.Duration(*addressdelay) * time.Second
+
+	// Wallet: 使用签名服务时主密钥及商只在签名服务中, API 进程只读写 meta
+	var keys *wallet.KeyRing
+	if len(*signerurl) > 0 {
+		if wallet.KeyRingConfigured(*masterkeyfile, "WALLET_MASTER_KEYS") {
+			panic(wallet.ErrMasterKeySigner)
+		}
+	} else if keys, err = wallet.LoadKeyRing(*masterkeyfile, "WALLET_MASTER_KEYS"); err != nil {
+		panic(err)
+	}
+	if keys == nil && len(*signerurl) == 0 && !*insecurelegacy {
+		panic(wallet.ErrNoMasterKey)
+	}
+	if keys == nil && len(*signerurl) == 0 {
+		log.Warnf("[Wallet] master key not configured, wallets encrypted in legacy format")
+	}
 	wltdb := &wallet.Mysql{
-		DBName: strings.ToLower(*wdbname),
-		DBHost: *dbhost,
-		DBUser: *dbuser,
-		DBPWD:  *dbpassword,
+		DBName:   strings.ToLower(*wdbname),
+		DBHost:   *dbhost,
+		DBUser:   *dbuser,
+		DBPWD:    *dbpassword,
+		Keys:     keys,
+		Cache:    wallet.NewAddressCache(*addresscache),
+		MetaOnly: len(*signerurl) > 0,
 	}
 	if err := wltdb.Open(); err != nil {
 		panic(err)
 	}
+	if *rotatekeys {
+		rotated, err := wltdb.RotateKeys()
+		if err != nil {
+			log.Errorf("[Wallet] RotateKeys rotated %d, error:%v", rotated, err)
+			return
+		}
+		log.Infof("[Wallet] RotateKeys rotated %d wallets to master key %d", rotated, keys.Current())
+		return
+	}
+
+	// Signer
+	var wltsigner signer.Signer = signer.NewLocalSigner(wltdb)
+	if len(*signerurl) > 0 {
+		wltsigner = signer.NewRemoteSigner(*signerurl, *signertoken, time.Duration(*signertimeout)*time.Second)
+	}
 	//初始化监控地址
 	wlts, _ := wltdb.GetWallets()
 	for _, wlt := range wlts {
-		pub, err := wlt.DerivePublicKey(ParseDerivationPath(COINTYPE))
+		addresses, err := WalletAddresses(wltsigner, wlt)
 		if err != nil {
-			log.Errorf("[Wallet] DerivePublicKey(%s) error:%v", wlt.Name, err)
+			log.Errorf("[Wallet] PublicKey(%s) error:%v", wlt.Name, err)
 		}
-		address := ToAddress(pub)
-		if err := db.AddMonitorAddress(address); err != nil {
-			log.Errorf("[Wallet] AddMonitorAddress(%s) error:%v", address, err)
+		for _, address := range addresses {
+			if err := db.AddMonitorAddress(address); err != nil {
+				log.Errorf("[Wallet] AddMonitorAddress(%s) error:%v", address, err)
+			}
 		}
 	}
 
+	// 钱包 meta 更新串行执行
+	addressLock := &sync.Mutex{}
+
+	// Gas price oracle
+	percentiles, err := ParsePercentiles(*gaspercentiles)
+	if err != nil {
+		panic(err)
+	}
+	oracle := NewGasOracle(db, *gasblocks, percentiles, time.Duration(*gascache)*time.Second)
+
 	// Scanning
-	go Scanning(context.Background(), db, db.RPC, big.NewInt(0))
+	go Scanning(context.Background(), db, db.RPC, oracle, big.NewInt(0))
+
+	// Tracking
+	tracker := NewTracker(db, wltdb, wltsigner, nonces, time.Duration(*bumptimeout)*time.Second, *bumppercent)
+	go tracker.Tracking(context.Background())
+
+	// Sweeping
+	threshold, ok := new(big.Int).SetString(*sweepthreshold, 10)
+	if !ok {
+		panic(fmt.Sprintf("invalid sweep threshold %s", *sweepthreshold))
+	}
+	sweeper := NewSweeper(db, wltdb, wltsigner, nonces, *sweepaddress, threshold, time.Duration(*sweepinterval)*time.Second)
+	if len(*sweepaddress) > 0 {
+		go sweeper.Sweeping(context.Background())
+	}
+
+	// Scheduling
+	scheduler := NewScheduler(db, wltdb, wltsigner, nonces, limits, oracle)
+	go scheduler.Scheduling(context.Background())
 
 	router := gin.Default()
 	router.POST("/changeprimarykey", func(c *gin.Context) {
@@ -109,8 +258,22 @@ func main() {
 		} else if len(req.Phone) == 0 || len(req.NewPhone) == 0 {
 			log.Errorf("[changePrimaryKey] empty %v or %v", req.Phone, req.NewPhone)
 			respone.ErrCode = codePhoneValidate
-		} else if err := wltdb.UpdateWalletName(req.Phone, req.NewPhone); err != nil {
-			log.Errorf("[changePrimaryKey] %v -> %v UpdateWalletName err %v", req.Phone, req.NewPhone, err)
+		} else if err := RenameWallet(wltdb, wltsigner, req.Phone, req.NewPhone); err != nil {
+			log.Errorf("[changePrimaryKey] %v -> %v RenameWallet err %v", req.Phone, req.NewPhone, err)
+			respone.ErrCode = codeWallet
+		} else if err := db.RenameSchedules(req.Phone, req.NewPhone); err != nil {
+			//钱包已迁移, 定时转账及订单紧随其后迁移
+			log.Errorf("[changePrimaryKey] %v -> %v RenameSchedules err %v", req.Phone, req.NewPhone, err)
+			respone.ErrCode = codeDB
+		} else if err := db.RenameOrders(req.Phone, req.NewPhone); err != nil {
+			log.Errorf("[changePrimaryKey] %v -> %v RenameOrders err %v", req.Phone, req.NewPhone, err)
+			respone.ErrCode = codeDB
+		} else if wlt, err := wltdb.GetWallet(req.NewPhone); err != nil || wlt == nil {
+			log.Errorf("[changePrimaryKey] %v GetWallet err %v", req.NewPhone, err)
+			respone.ErrCode = codeWallet
+		} else if SetRenamed(wlt, time.Now()); false {
+		} else if err := wltdb.UpdateWallet(wlt); err != nil {
+			log.Errorf("[changePrimaryKey] %v UpdateWallet err %v", req.NewPhone, err)
 			respone.ErrCode = codeWallet
 		}
 		respone.Data = "change success"
@@ -129,15 +292,15 @@ func main() {
 		} else if err := sms.VailMobile(req.Phone); err != nil {
 			log.Errorf("[getaddressinfo] %v VailMobile err %v", req.Phone, err)
 			respone.ErrCode = codePhoneValidate
-		} else if wlt, err := wltdb.InsertOrGetWallet(req.Phone); err != nil {
+		} else if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
 			log.Errorf("[getaddressinfo] %v InsertOrGetWallet err %v", req.Phone, err)
 			respone.ErrCode = codeWallet
-		} else if pub, err := wlt.DerivePublicKey(ParseDerivationPath(COINTYPE)); err != nil {
-			log.Errorf("[getaddressinfo] %v DerivePublicKey err %v", req.Phone, err)
+		} else if address, err := DefaultAddress(wltsigner, wlt); err != nil {
+			log.Errorf("[getaddressinfo] %v PublicKey err %v", req.Phone, err)
 			respone.ErrCode = codeWallet
 		} else {
 			addressInfo := &AddressInfoRespone{
-				Address:      ToAddress(pub),
+				Address:      address,
 				TokenAddress: req.TokenAddress,
 				Coin:         "urac",
 				Decimal:      18,
@@ -151,24 +314,216 @@ func main() {
 					addressInfo.Decimal = uint32(tokenInfo.Decimal)
 				}
 			}
-			if amount, err := db.GetAmount(strings.ToLower(addressInfo.Address), strings.ToLower(addressInfo.TokenAddress)); err != nil {
-				log.Errorf("[getaddressinfo] %v GetAmount err %v %v", req.Phone, req.TokenAddress, err)
-				respone.ErrCode = codeDB
-			} else {
-				addressInfo.Amount = amount
+			addressInfo.Amount, addressInfo.TotalAmount = big.NewInt(0), big.NewInt(0)
+			for _, index := range wlt.AddressIndexes() {
+				if address, err := IndexAddress(wltsigner, wlt, index); err != nil {
+					log.Errorf("[getaddressinfo] %v PublicKey err %v", req.Phone, err)
+					respone.ErrCode = codeWallet
+				} else if amount, err := db.GetAmount(address, strings.ToLower(addressInfo.TokenAddress)); err != nil {
+					log.Errorf("[getaddressinfo] %v GetAmount err %v %v", req.Phone, req.TokenAddress, err)
+					respone.ErrCode = codeDB
+				} else {
+					//未指定 from 时转账只从默认地址支出, amount 为默认地址余额
+					if strings.EqualFold(address, addressInfo.Address) {
+						addressInfo.Amount = amount
+					}
+					addressInfo.TotalAmount.Add(addressInfo.TotalAmount, amount)
+					addressInfo.Addresses = append(addressInfo.Addresses, &AddressAmount{
+						Address:    address,
+						Account:    index.Account,
+						Index:      index.Index,
+						Passphrase: index.Passphrase,
+						Amount:     amount,
+					})
+				}
 			}
-			if gasprice, err := db.GetGasPrice(); err != nil {
+			if gasprice, err := oracle.GasPrice(SpeedStandard); err != nil {
 				log.Errorf("[getaddressinfo] %v GetGasPrice err %v %v", req.Phone, req.TokenAddress, err)
 				respone.ErrCode = codeDB
 			} else {
 				addressInfo.GasPrice = gasprice
 			}
+			addressInfo.AmountDecimal = unit.Format(addressInfo.Amount, int64(addressInfo.Decimal))
+			addressInfo.TotalAmountDecimal = unit.Format(addressInfo.TotalAmount, int64(addressInfo.Decimal))
+			for _, address := range addressInfo.Addresses {
+				address.AmountDecimal = unit.Format(address.Amount, int64(addressInfo.Decimal))
+			}
 			respone.Data = addressInfo
 		}
 		respone.ErrMsg = msgs[respone.ErrCode]
 		respone.Hash = respone.MD5()
 		c.JSON(http.StatusOK, respone)
 	})
+	router.POST("/newaddress", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &NewAddressRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[newaddress] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if err := sms.VailMobile(req.Phone); err != nil {
+			log.Errorf("[newaddress] %v VailMobile err %v", req.Phone, err)
+			respone.ErrCode = codePhoneValidate
+		} else if req.Account >= 0x80000000 {
+			log.Errorf("[newaddress] %v invalide account %v", req.Phone, req.Account)
+			respone.ErrCode = codeRequest
+		} else {
+			addressLock.Lock()
+			if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+				log.Errorf("[newaddress] %v InsertOrGetWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if wlt.WatchOnly() && req.Account != 0 {
+				//观察钱包只有扩展公钥对应的一个账户
+				log.Errorf("[newaddress] %v watch-only wallet account %v", req.Phone, req.Account)
+				respone.ErrCode = codeRequest
+			} else if index := wlt.NextAddressIndex(req.Account); false {
+			} else if address, err := AllocateAddress(wltsigner, wlt, index, req.Passphrase); err != nil {
+				log.Errorf("[newaddress] %v AllocateAddress err %v", req.Phone, err)
+				respone.ErrCode = walletErrCode(err, codeWallet)
+			} else if err := wltdb.UpdateWallet(wlt); err != nil {
+				log.Errorf("[newaddress] %v UpdateWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if err := db.AddMonitorAddress(address); err != nil {
+				log.Errorf("[newaddress] %v AddMonitorAddress err %v", req.Phone, err)
+				respone.ErrCode = codeDB
+			} else {
+				respone.Data = &AddressAmount{
+					Address:    address,
+					Account:    index.Account,
+					Index:      index.Index,
+					Passphrase: index.Passphrase,
+					Amount:     big.NewInt(0),
+
+					AmountDecimal: "0",
+				}
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/addaddress", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &AddressBookRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[addaddress] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "addaddress", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else if !ValidAddress(req.Address) {
+			log.Errorf("[addaddress] %v invalide address %v", req.Phone, req.Address)
+			respone.ErrCode = codeAddrValidate
+		} else {
+			addressLock.Lock()
+			if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+				log.Errorf("[addaddress] %v InsertOrGetWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if entry := AddBookAddress(wlt, req.Address, req.Label); false {
+			} else if err := wltdb.UpdateWallet(wlt); err != nil {
+				log.Errorf("[addaddress] %v UpdateWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else {
+				getsessions(c).Delete(req.Phone)
+				respone.Data = entry
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/removeaddress", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &AddressBookRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[removeaddress] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "removeaddress", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else {
+			addressLock.Lock()
+			if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+				log.Errorf("[removeaddress] %v InsertOrGetWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if !RemoveBookAddress(wlt, req.Address) {
+				respone.ErrCode = codeAddrValidate
+			} else if err := wltdb.UpdateWallet(wlt); err != nil {
+				log.Errorf("[removeaddress] %v UpdateWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else {
+				getsessions(c).Delete(req.Phone)
+				respone.Data = AddressBook(wlt)
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/listaddress", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &AddressBookRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[listaddress] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if err := sms.VailMobile(req.Phone); err != nil {
+			log.Errorf("[listaddress] %v VailMobile err %v", req.Phone, err)
+			respone.ErrCode = codePhoneValidate
+		} else if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+			log.Errorf("[listaddress] %v InsertOrGetWallet err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else {
+			respone.Data = &AddressBookRespone{
+				WhitelistOnly:    WhitelistOnly(wlt),
+				WhitelistOffTime: WhitelistOffTime(wlt),
+				Addresses:        AddressBook(wlt),
+			}
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/setwhitelistonly", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &WhitelistOnlyRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[setwhitelistonly] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "setwhitelistonly", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else {
+			addressLock.Lock()
+			if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+				log.Errorf("[setwhitelistonly] %v InsertOrGetWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if SetWhitelistOnly(wlt, req.Enabled, time.Duration(*addressdelay)*time.Second); false {
+			} else if err := wltdb.UpdateWallet(wlt); err != nil {
+				log.Errorf("[setwhitelistonly] %v UpdateWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else {
+				getsessions(c).Delete(req.Phone)
+				respone.Data = &AddressBookRespone{
+					WhitelistOnly:    WhitelistOnly(wlt),
+					WhitelistOffTime: WhitelistOffTime(wlt),
+					Addresses:        AddressBook(wlt),
+				}
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
 	router.POST("/gethistoryinfo", func(c *gin.Context) {
 		respone := &common.APIRespone{
 			ErrCode: codeOk,
@@ -183,16 +538,27 @@ func main() {
 		} else if err := sms.VailMobile(req.Phone); err != nil {
 			log.Errorf("[gethistoryinfo] %v VailMobile err %v", req.Phone, err)
 			respone.ErrCode = codePhoneValidate
-		} else if wlt, err := wltdb.InsertOrGetWallet(req.Phone); err != nil {
+		} else if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
 			log.Errorf("[gethistoryinfo] %v InsertOrGetWallet err %v", req.Phone, err)
 			respone.ErrCode = codeWallet
-		} else if pub, err := wlt.DerivePublicKey(ParseDerivationPath(COINTYPE)); err != nil {
-			log.Errorf("[gethistoryinfo] %v DerivePublicKey err %v", req.Phone, err)
+		} else if addresses, err := WalletAddresses(wltsigner, wlt); err != nil {
+			log.Errorf("[gethistoryinfo] %v PublicKey err %v", req.Phone, err)
 			respone.ErrCode = codeWallet
-		} else if htxs, err := db.GetHistory(strings.ToLower(ToAddress(pub)), strings.ToLower(req.TokenAddress), req.PageSize, req.PageNum); err != nil {
+		} else if htxs, err := db.GetHistories(addresses, strings.ToLower(req.TokenAddress), req.PageSize, req.PageNum); err != nil {
 			log.Errorf("[gethistoryinfo] %v GetHistory err %v", req.Phone, err)
 			respone.ErrCode = codeDB
+		} else if decimal, ok, err := db.LookupTokenDecimal(req.TokenAddress); err != nil {
+			log.Errorf("[gethistoryinfo] %v LookupTokenDecimal err %v %v", req.Phone, req.TokenAddress, err)
+			respone.ErrCode = codeDB
 		} else {
+			//金额按币种精度, 手续费按原生币精度; 未记录的 token 没有记录, 不返回十进制金额
+			for _, htx := range htxs {
+				if ok {
+					htx.ValueDecimal = unit.Format(htx.Value, decimal)
+					htx.TValueDecimal = unit.Format(htx.TValue, decimal)
+				}
+				htx.FeeDecimal = unit.Format(htx.Fee, COINDECIMAL)
+			}
 			respone.Data = htxs
 		}
 		respone.ErrMsg = msgs[respone.ErrCode]
@@ -235,14 +601,44 @@ func main() {
 			respone.ErrCode = codeRequest
 		} else if len(req.Hash) == 0 {
 			respone.ErrCode = codeHash
+		} else if req.TokenAddress != "" && !ValidAddress(req.TokenAddress) {
+			log.Errorf("[gettxinfo] %v invalide token address %v", req.Phone, req.TokenAddress)
+			respone.ErrCode = codeAddrValidate
+		} else if decimal, ok, err := db.LookupTokenDecimal(req.TokenAddress); err != nil {
+			log.Errorf("[gettxinfo] %v LookupTokenDecimal err %v %v", req.Phone, req.TokenAddress, err)
+			respone.ErrCode = codeDB
 		} else if curBlock, err := db.GetBlockChain(); err != nil {
 			log.Errorf("[gettxinfo] %v GetBlockChain err %v", req.Phone, err)
 			respone.ErrCode = codeDB
 		} else if tx, err := db.RPC.GetTransaction(req.Hash); err != nil {
 			log.Errorf("[gettxinfo] %v GetTransaction err %v", req.Phone, err)
 			respone.ErrCode = codeRPC
+		} else if orderInfo, err := db.GetOrderByHash(req.Hash); err != nil {
+			log.Errorf("[gettxinfo] %v GetOrderByHash err %v", req.Phone, err)
+			respone.ErrCode = codeDB
+		} else if tx == nil && orderInfo != nil {
+			respone.Data = &common.HistoryInfo{
+				Hash:     orderInfo.Hash,
+				From:     orderInfo.From,
+				To:       orderInfo.To,
+				Value:    orderInfo.Value,
+				Fee:      orderInfo.Fee,
+				Time:     orderInfo.Time,
+				OrderID:  orderInfo.ID,
+				TxStatus: orderStatus[orderInfo.Status],
+			}
+		} else if tx == nil {
+			respone.ErrCode = codeTxNotFound
 		} else {
-			tokenAddress := ""
+			tokenAddress := strings.ToLower(req.TokenAddress)
+			//指定 token 时只统计该 token 的转账, 否则只统计原生币
+			isLeg := func(addresses []string) bool {
+				joined := strings.ToLower(strings.Join(addresses, ","))
+				if len(tokenAddress) == 0 {
+					return !strings.Contains(joined, "-")
+				}
+				return strings.HasSuffix(joined, fmt.Sprintf("-%s", tokenAddress))
+			}
 			ttx := &common.HistoryInfo{
 				Hash:      tx.ID,
 				Time:      tx.Time,
@@ -257,10 +653,14 @@ func main() {
 			if ttx.Confirmations > 6 {
 				ttx.Status = 1
 			}
+			if orderInfo != nil {
+				ttx.OrderID = orderInfo.ID
+				ttx.TxStatus = orderStatus[orderInfo.Status]
+			}
 			var ins []*InOut
 			ivalue := big.NewInt(0)
 			for _, in := range tx.Ins {
-				if strings.Contains(strings.Join(in.Addresses, ","), "-") {
+				if !isLeg(in.Addresses) {
 					continue
 				}
 				ivalue = new(big.Int).Add(ivalue, in.Value)
@@ -269,7 +669,7 @@ func main() {
 			var outs []*InOut
 			ovalue := big.NewInt(0)
 			for _, out := range tx.Outs {
-				if strings.Contains(strings.Join(out.Addresses, ","), "-") {
+				if !isLeg(out.Addresses) {
 					continue
 				}
 				ovalue = new(big.Int).Add(ovalue, out.Value)
@@ -289,6 +689,11 @@ func main() {
 				ttx.From = strings.Replace(string(insStr), fmt.Sprintf("-%s", tokenAddress), "", -1)
 				ttx.To = strings.Replace(string(outsStr), fmt.Sprintf("-%s", tokenAddress), "", -1)
 			}
+			if ok {
+				ttx.ValueDecimal = unit.Format(ttx.Value, decimal)
+				ttx.TValueDecimal = unit.Format(ttx.TValue, decimal)
+			}
+			ttx.FeeDecimal = unit.Format(ttx.Fee, COINDECIMAL)
 			respone.Data = ttx
 		}
 		respone.ErrMsg = msgs[respone.ErrCode]
@@ -337,170 +742,1614 @@ func main() {
 		if err := c.BindJSON(&req); err != nil {
 			log.Errorf("[send] %v BindJSON err %v", req.Phone, err)
 			respone.ErrCode = codeRequest
-		} else if skip := inlist(skiplist, req.Phone); false {
-
-		} else if err := sms.VailMobile(req.Phone); err != nil {
-			log.Errorf("[send] %v VailMobile err %v", req.Phone, err)
-			respone.ErrCode = codePhoneValidate
-		} else if err := sms.VailCode(req.Code); !skip && err != nil {
-			log.Errorf("[send] %v VailCode err %v", req.Phone, err)
-			respone.ErrCode = codeSMSValidate
+		} else if code := verifyCode(c, "send", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
 		} else if len(req.Order) == 0 {
 			respone.ErrCode = codeOrder
-		} else if token, ok := getsessions(c).Get(req.Phone).(*Token); !skip && !ok {
-			respone.ErrCode = codeSMSValidate
-		} else if !skip && strings.Compare(token.SendTxCode, req.Code) != 0 {
-			respone.ErrCode = codeSMSValidate
-		} else if !skip && time.Now().Sub(time.Unix(token.SendTxTime, 0)) > 600*time.Second {
-			respone.ErrCode = codeSMSExpire
 		} else if req.TokenAddress != "" && !ValidAddress(req.TokenAddress) {
 			log.Errorf("[send] %v invalide token address %v", req.Phone, req.TokenAddress)
 			respone.ErrCode = codeAddrValidate
-		} else if wlt, err := wltdb.InsertOrGetWallet(req.Phone); err != nil {
+		} else if req.From != "" && !ValidAddress(req.From) {
+			log.Errorf("[send] %v invalide from address %v", req.Phone, req.From)
+			respone.ErrCode = codeAddrValidate
+		} else if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
 			log.Errorf("[send] %v InsertOrGetWallet err %v", req.Phone, err)
 			respone.ErrCode = codeWallet
-		} else if pub, err := wlt.DerivePublicKey(ParseDerivationPath(COINTYPE)); err != nil {
-			log.Errorf("[send] %v DerivePublicKey err %v", req.Phone, err)
-			respone.ErrCode = codeWallet
-		} else if privateKey, err := wlt.DerivePrivateKey(ParseDerivationPath(COINTYPE)); err != nil {
-			log.Errorf("[send] %v DerivePrivateKey err %v", req.Phone, err)
-			respone.ErrCode = codeWallet
+		} else if account, err := FromAccount(wltsigner, wlt, req.From, req.Passphrase); err != nil {
+			log.Errorf("[send] %v FromAccount err %v", req.Phone, err)
+			respone.ErrCode = walletErrCode(err, codeWallet)
+		} else if rules := limits.Rules(wlt); false {
+		} else if err := limits.CheckAccount(rules, wlt, len(req.Order)); err != nil {
+			log.Errorf("[send] %v CheckAccount err %v", req.Phone, err)
+			respone.ErrCode = codeLimit
+			respone.Data = err.Error()
 		} else {
 			getsessions(c).Delete(req.Phone)
-			from := ToAddress(pub)
-			if len(req.TokenAddress) > 0 {
-				//TODO
+			from := strings.ToLower(ToAddress(account.PublicKey))
+			tokenAddress := strings.ToLower(req.TokenAddress)
+			nonces.Lock(from)
+			defer nonces.Unlock(from)
+			limits.Lock(wlt.Name)
+			defer limits.Unlock(wlt.Name)
+			//余额以节点为准, 扫描数据可能滞后
+			if amount, _, err := db.RPC.GetBalanceAndNone(from, ""); err != nil {
+				log.Errorf("[send] %v GetBalanceAndNone err %v", req.Phone, err)
+				respone.ErrCode = codeRPC
+			} else if tokenAmount, err := db.RPC.getBalance(from, tokenAddress, nil); err != nil {
+				log.Errorf("[send] %v getBalance err %v %v", req.Phone, req.TokenAddress, err)
+				respone.ErrCode = codeRPC
+			} else if decimal, err := db.TokenDecimal(tokenAddress); err != nil {
+				log.Errorf("[send] %v TokenDecimal err %v %v", req.Phone, req.TokenAddress, err)
+				respone.ErrCode = codeDB
 			} else {
+				guard := limits.Guard(wlt, rules)
 				res := map[string]string{}
-				amount, nonce, _ := db.RPC.GetBalanceAndNone(strings.ToLower(from), strings.ToLower(req.TokenAddress))
+				results := []*OrderResult{}
+				orderInfos := []*OrderInfo{}
+				invalid := false
+				resultByID := map[string]*OrderResult{}
+				seen := map[string]bool{}
 				for _, order := range req.Order {
+					result := &OrderResult{ID: order.ID}
+					results = append(results, result)
+					fail := func(msg string) {
+						res[order.ID] = msg
+						result.Error = msg
+						invalid = true
+					}
+					if orderInfo, err := CheckOrderID(db, req.Phone, order.ID, seen); err != nil {
+						fail(err.Error())
+						continue
+					} else if orderInfo != nil {
+						//重复订单, 返回已有结果
+						res[order.ID] = orderInfo.Hash
+						result.Hash = orderInfo.Hash
+						continue
+					}
+					resultByID[order.ID] = result
+					if value, err := unit.Amount(&order.Value, order.ValueDecimal, decimal); err != nil {
+						fail(err.Error())
+						continue
+					} else if err := ValidValue(value); err != nil {
+						fail(err.Error())
+						continue
+					} else {
+						order.Value = *value
+					}
+					if gasprice, err := unit.Amount(&order.GasPrice, order.GasPriceDecimal, COINDECIMAL); err != nil {
+						fail(err.Error())
+						continue
+					} else {
+						order.GasPrice = *gasprice
+					}
 					if order.Gas == 0 {
 						order.Gas = 21000
+						if len(tokenAddress) > 0 {
+							order.Gas = TransferGas
+						}
 					}
 					if order.GasPrice.Cmp(big.NewInt(0)) == 0 {
-						gasprice, err := db.RPC.GetGasPrice()
+						gasprice, err := oracle.GasPrice(order.Speed)
 						if err != nil {
-							res[order.ID] = err.Error()
+							fail(err.Error())
 							continue
 						}
-						gasprice.Sub(gasprice, new(big.Int).SetBytes(gasprice.Bytes()).Mod(new(big.Int).SetBytes(gasprice.Bytes()), big.NewInt(1e9)))
 						order.GasPrice = *gasprice
 					}
-					if amount.Cmp(new(big.Int).Add(&order.Value, new(big.Int).Mul(&order.GasPrice, new(big.Int).SetInt64(order.Gas)))) < 0 {
-						res[order.ID] = fmt.Sprintf("not sufficient funds %v < %v", amount, new(big.Int).Mul(&order.GasPrice, new(big.Int).SetInt64(order.Gas)))
-					} else if signedhash, err := CreateTx(privateKey, nonce.Uint64(), order.To, &order.Value, uint64(order.Gas), &order.GasPrice, nil); err != nil {
-						res[order.ID] = err.Error()
-					} else if hash, err := db.RPC.SendRawTransaction(fmt.Sprintf("0x%s", signedhash)); err != nil {
-						res[order.ID] = err.Error()
+					fee := new(big.Int).Mul(&order.GasPrice, new(big.Int).SetInt64(order.Gas))
+					//原生币 转账金额+手续费, token 只消耗手续费
+					cost := new(big.Int).Add(&order.Value, fee)
+					if len(tokenAddress) > 0 {
+						cost = fee
+					}
+					if !ValidAddress(order.To) {
+						fail(fmt.Sprintf("invalidate address %v", order.To))
+					} else if amount.Cmp(cost) < 0 {
+						fail(fmt.Sprintf("not sufficient funds %v < %v", amount, cost))
+					} else if len(tokenAddress) > 0 && tokenAmount.Cmp(&order.Value) < 0 {
+						fail(fmt.Sprintf("not sufficient token funds %v < %v", tokenAmount, &order.Value))
+					} else {
+						orderInfos = append(orderInfos, &OrderInfo{
+							ID:           order.ID,
+							Phone:        req.Phone,
+							Type:         OrderTransfer,
+							From:         from,
+							TokenAddress: tokenAddress,
+							To:           strings.ToLower(order.To),
+							Value:        new(big.Int).Set(&order.Value),
+							Gas:          uint64(order.Gas),
+							GasPrice:     new(big.Int).Set(&order.GasPrice),
+						})
+						//批量累计金额
+						amount = new(big.Int).Sub(amount, cost)
+						if len(tokenAddress) > 0 {
+							tokenAmount = new(big.Int).Sub(tokenAmount, &order.Value)
+						}
+					}
+				}
+				if req.Atomic {
+					//全部校验通过才签名, 全部签名成功才广播
+					ok := !invalid
+					if ok {
+						var sent []*OrderResult
+						sent, ok = SendOrdersAtomic(db, nonces, guard, account, orderInfos)
+						for _, r := range sent {
+							res[r.ID] = r.Hash
+							*resultByID[r.ID] = *r
+						}
+					}
+					if ok {
+						respone.Data = res
 					} else {
-						res[order.ID] = hash
+						respone.ErrCode = codeBatch
+						respone.Data = results
+					}
+				} else {
+					for _, orderInfo := range orderInfos {
+						if err := SendOrder(db, nonces, guard, account, orderInfo); err != nil {
+							res[orderInfo.ID] = err.Error()
+						} else {
+							res[orderInfo.ID] = orderInfo.Hash
+						}
 					}
-					amount = new(big.Int).Sub(amount, new(big.Int).Add(&order.Value, new(big.Int).Mul(&order.GasPrice, new(big.Int).SetInt64(order.Gas))))
-					nonce = new(big.Int).Add(nonce, big.NewInt(1))
+					respone.Data = res
 				}
-				respone.Data = res
 			}
 		}
 		respone.ErrMsg = msgs[respone.ErrCode]
 		respone.Hash = respone.MD5()
 		c.JSON(http.StatusOK, respone)
 	})
-	router.POST("/getfee", func(c *gin.Context) {
+	contractHandler := func(tag string, deploy bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			respone := &common.APIRespone{
+				ErrCode: codeOk,
+			}
+			req := &ContractRequest{}
+			if err := c.BindJSON(&req); err != nil {
+				log.Errorf("[%s] %v BindJSON err %v", tag, req.Phone, err)
+				respone.ErrCode = codeRequest
+			} else if code := verifyCode(c, tag, req.Phone, req.Code); code != codeOk {
+				respone.ErrCode = code
+			} else if ReservedOrderID(req.ID) {
+				log.Errorf("[%s] %v order id %v reserved", tag, req.Phone, req.ID)
+				respone.ErrCode = codeRequest
+				respone.Data = fmt.Sprintf("order id %s reserved", req.ID)
+			} else if !deploy && !ValidAddress(req.To) {
+				log.Errorf("[%s] %v invalide contract address %v", tag, req.Phone, req.To)
+				respone.ErrCode = codeAddrValidate
+			} else if data, err := ContractData(req.Data, req.Method, req.Args, deploy); err != nil {
+				log.Errorf("[%s] %v ContractData err %v", tag, req.Phone, err)
+				respone.ErrCode = codeRequest
+			} else if err := ValidValue(&req.Value); err != nil {
+				log.Errorf("[%s] %v invalide value %v", tag, req.Phone, err)
+				respone.ErrCode = codeRequest
+				respone.Data = err.Error()
+			} else if orderInfo, err := db.GetOrder(req.Phone, req.ID); err != nil {
+				log.Errorf("[%s] %v GetOrder err %v", tag, req.Phone, err)
+				respone.ErrCode = codeDB
+			} else if orderInfo != nil && orderInfo.Status != OrderFailed {
+				//重复订单, 返回已有结果
+				respone.Data = orderInfo
+			} else if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+				log.Errorf("[%s] %v InsertOrGetWallet err %v", tag, req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if account, err := DefaultAccount(wltsigner, wlt, req.Passphrase); err != nil {
+				log.Errorf("[%s] %v DefaultAccount err %v", tag, req.Phone, err)
+				respone.ErrCode = walletErrCode(err, codeWallet)
+			} else if rules := limits.Rules(wlt); false {
+			} else if err := limits.CheckAccount(rules, wlt, 1); err != nil {
+				log.Errorf("[%s] %v CheckAccount err %v", tag, req.Phone, err)
+				respone.ErrCode = codeLimit
+				respone.Data = err.Error()
+			} else {
+				getsessions(c).Delete(req.Phone)
+				from := strings.ToLower(ToAddress(account.PublicKey))
+				to := ""
+				if !deploy {
+					to = strings.ToLower(req.To)
+				}
+				nonces.Lock(from)
+				defer nonces.Unlock(from)
+				limits.Lock(wlt.Name)
+				defer limits.Unlock(wlt.Name)
+				if req.Gas == 0 {
+					if gas, err := db.RPC.EstimateGas(from, to, &req.Value, data); err != nil {
+						log.Errorf("[%s] %v EstimateGas err %v", tag, req.Phone, err)
+						respone.ErrCode = codeRPC
+					} else {
+						req.Gas = new(big.Int).Div(new(big.Int).Mul(gas, big.NewInt(100+*gasmargin)), big.NewInt(100)).Int64()
+					}
+				}
+				if respone.ErrCode == codeOk && req.GasPrice.Cmp(big.NewInt(0)) == 0 {
+					if gasprice, err := oracle.GasPrice(SpeedStandard); err != nil {
+						log.Errorf("[%s] %v GasPrice err %v", tag, req.Phone, err)
+						respone.ErrCode = codeRPC
+					} else {
+						req.GasPrice = *gasprice
+					}
+				}
+				cost := new(big.Int).Add(&req.Value, new(big.Int).Mul(&req.GasPrice, big.NewInt(req.Gas)))
+				orderInfo := &OrderInfo{
+					ID:       req.ID,
+					Phone:    req.Phone,
+					Type:     OrderContract,
+					From:     from,
+					To:       to,
+					Value:    new(big.Int).Set(&req.Value),
+					Data:     fmt.Sprintf("0x%x", data),
+					Gas:      uint64(req.Gas),
+					GasPrice: new(big.Int).Set(&req.GasPrice),
+				}
+				if respone.ErrCode != codeOk {
+				} else if amount, _, err := db.RPC.GetBalanceAndNone(from, ""); err != nil {
+					log.Errorf("[%s] %v GetBalanceAndNone err %v", tag, req.Phone, err)
+					respone.ErrCode = codeRPC
+				} else if amount.Cmp(cost) < 0 {
+					log.Errorf("[%s] %v not sufficient funds %v < %v", tag, req.Phone, amount, cost)
+					respone.ErrCode = codeFunds
+				} else if err := SendOrder(db, nonces, limits.Guard(wlt, rules), account, orderInfo); err != nil {
+					log.Errorf("[%s] %v SendOrder err %v", tag, req.Phone, err)
+					respone.ErrCode = ruleErrCode(err, codeRPC)
+					respone.Data = orderInfo
+					if respone.ErrCode != codeRPC {
+						respone.Data = err.Error()
+					}
+				} else {
+					respone.Data = orderInfo
+				}
+			}
+			respone.ErrMsg = msgs[respone.ErrCode]
+			respone.Hash = respone.MD5()
+			c.JSON(http.StatusOK, respone)
+		}
+	}
+	router.POST("/callcontract", contractHandler("callcontract", false))
+	router.POST("/deploycontract", contractHandler("deploycontract", true))
+	router.POST("/querycontract", func(c *gin.Context) {
 		respone := &common.APIRespone{
 			ErrCode: codeOk,
 		}
-		req := &AddressInfoRequest{}
+		req := &QueryContractRequest{}
 		if err := c.BindJSON(&req); err != nil {
-			log.Errorf("[getfee] %v BindJSON err %v", req.Phone, err)
+			log.Errorf("[querycontract] %v BindJSON err %v", req.To, err)
 			respone.ErrCode = codeRequest
-		} else if gasprice, err := db.GetGasPrice(); err != nil {
-			log.Errorf("[getfee] %v GetGasPrice err %v", req.Phone, err)
+		} else if !ValidAddress(req.To) || (req.From != "" && !ValidAddress(req.From)) {
+			log.Errorf("[querycontract] invalide address %v %v", req.From, req.To)
+			respone.ErrCode = codeAddrValidate
+		} else if data, err := ContractData(req.Data, req.Method, req.Args, false); err != nil {
+			log.Errorf("[querycontract] %v ContractData err %v", req.To, err)
+			respone.ErrCode = codeRequest
+		} else if result, err := db.RPC.Call(strings.ToLower(req.From), strings.ToLower(req.To), data); err != nil {
+			log.Errorf("[querycontract] %v Call err %v", req.To, err)
 			respone.ErrCode = codeRPC
 		} else {
-			gasprice.Sub(gasprice, new(big.Int).SetBytes(gasprice.Bytes()).Mod(new(big.Int).SetBytes(gasprice.Bytes()), big.NewInt(1e9)))
-			fee := &Fee{
-				Gas:      21000,
-				GasPrice: *gasprice,
+			queryResult := &QueryContractRespone{
+				Data: fmt.Sprintf("0x%x", result),
 			}
-			if len(req.TokenAddress) > 0 {
-				// TODO
+			if len(req.Outputs) > 0 {
+				if values, err := abi.Unpack(req.Outputs, result); err != nil {
+					log.Errorf("[querycontract] %v Unpack err %v", req.To, err)
+					respone.ErrCode = codeRequest
+				} else {
+					queryResult.Values = values
+				}
 			}
-			respone.Data = fee
+			respone.Data = queryResult
 		}
 		respone.ErrMsg = msgs[respone.ErrCode]
 		respone.Hash = respone.MD5()
 		c.JSON(http.StatusOK, respone)
 	})
-	if err := router.Run(fmt.Sprintf(":%d", *listenport)); err != nil {
-		panic(err)
-	}
-}
-
-// ChangePrimaryKeyRequest 修改
-type ChangePrimaryKeyRequest struct {
-	Phone    string `json:"phone" binding:"required"`
-	NewPhone string `json:"new_phone" binding:"required"`
-}
-
-//AddressInfoRequest 地址请求
-type AddressInfoRequest struct {
-	Phone        string `json:"phone" binding:"required"`
-	TokenAddress string `json:"token_address"` //token 地址
-}
-
-//HistoryInfoRequest 历史请求
-type HistoryInfoRequest struct {
-	Phone        string `json:"phone" binding:"required"`
-	TokenAddress string `json:"token_address"`
-	PageNum      int64  `json:"page_num"`
-	PageSize     int64  `json:"page_size"`
-}
-
-//BlkInfoRequest 区块请求
-type BlkInfoRequest struct {
-	Height int64 `json:"height" binding:"required"`
-}
-
-//TxInfoRequest 交易请求
-type TxInfoRequest struct {
-	Phone        string `json:"phone" binding:"required"`
-	TokenAddress string `json:"token_address"`
-	Hash         string `json:"hash"`
-}
-
-// ConfirmRequest 发送交易验证码
-type ConfirmRequest struct {
-	Phone string `json:"phone" binding:"required"`
-}
-
-// SendRequest 发送交易
-type SendRequest struct {
-	Phone        string   `json:"phone" binding:"required"`
-	TokenAddress string   `json:"token_address"` //token 地址
-	Code         string   `json:"code"`          //验证码
-	Order        []*Order `json:"order"`         //订单列表
-}
-
-// Order 订单
-type Order struct {
-	ID       string  `json:"id"`        //id
-	To       string  `json:"to"`        //接收方
-	Value    big.Int `json:"value"`     //接收金额
-	Gas      int64   `json:"gas"`       //手续费
-	GasPrice big.Int `json:"gas_price"` //手续费
-}
-
-//AddressInfoRespone 地址信息
-type AddressInfoRespone struct {
-	Address      string   `json:"address"`       //拥有的地址
+	router.POST("/sweep", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &SweepRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[sweep] BindJSON err %v", err)
+			respone.ErrCode = codeRequest
+		} else if !isAdmin(req.Token) {
+			log.Errorf("[sweep] unauthorized")
+			respone.ErrCode = codeAuthorize
+		} else if orderInfos, err := sweeper.Sweep(); err != nil {
+			log.Errorf("[sweep] Sweep err %v", err)
+			respone.ErrCode = codeRPC
+		} else {
+			respone.Data = orderInfos
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/getsweeps", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &SweepRequest{
+			PageNum:  0,
+			PageSize: 20,
+		}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[getsweeps] BindJSON err %v", err)
+			respone.ErrCode = codeRequest
+		} else if !isAdmin(req.Token) {
+			log.Errorf("[getsweeps] unauthorized")
+			respone.ErrCode = codeAuthorize
+		} else if orderInfos, err := db.GetOrdersByPrefix(OrderSweep, sweepPrefix, req.PageSize, req.PageNum); err != nil {
+			log.Errorf("[getsweeps] GetOrdersByPrefix err %v", err)
+			respone.ErrCode = codeDB
+		} else {
+			respone.Data = orderInfos
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/setlimits", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &LimitRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[setlimits] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if !isAdmin(req.Token) {
+			log.Errorf("[setlimits] %v unauthorized", req.Phone)
+			respone.ErrCode = codeAuthorize
+		} else {
+			addressLock.Lock()
+			if wlt, err := wltdb.GetWallet(req.Phone); err != nil {
+				log.Errorf("[setlimits] %v GetWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if wlt == nil {
+				respone.ErrCode = codeWallet
+			} else if SetUserRules(wlt, req.Rules); false {
+			} else if err := wltdb.UpdateWallet(wlt); err != nil {
+				log.Errorf("[setlimits] %v UpdateWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else {
+				respone.Data = limits.Rules(wlt)
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/getlimits", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &LimitRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[getlimits] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if !isAdmin(req.Token) {
+			log.Errorf("[getlimits] %v unauthorized", req.Phone)
+			respone.ErrCode = codeAuthorize
+		} else if wlt, err := wltdb.GetWallet(req.Phone); err != nil {
+			log.Errorf("[getlimits] %v GetWallet err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else if wlt == nil {
+			respone.ErrCode = codeWallet
+		} else {
+			respone.Data = &LimitRespone{
+				Rules: limits.Rules(wlt),
+				User:  UserRules(wlt),
+			}
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/schedule", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &ScheduleRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[schedule] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "schedule", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else if !ValidAddress(req.To) || (req.TokenAddress != "" && !ValidAddress(req.TokenAddress)) {
+			log.Errorf("[schedule] %v invalide address %v %v", req.Phone, req.TokenAddress, req.To)
+			respone.ErrCode = codeAddrValidate
+		} else if next, err := NextRun(req.Cron, time.Now()); err != nil {
+			log.Errorf("[schedule] %v invalide cron %v %v", req.Phone, req.Cron, err)
+			respone.ErrCode = codeRequest
+		} else if req.At == 0 && next == 0 {
+			log.Errorf("[schedule] %v no execution time", req.Phone)
+			respone.ErrCode = codeRequest
+		} else if req.At != 0 && req.At < time.Now().Unix() {
+			log.Errorf("[schedule] %v execution time %v passed", req.Phone, req.At)
+			respone.ErrCode = codeRequest
+		} else if req.Policy != "" && req.Policy != PolicyRetry && req.Policy != PolicySkip {
+			log.Errorf("[schedule] %v invalide policy %v", req.Phone, req.Policy)
+			respone.ErrCode = codeRequest
+		} else if scheduleInfo, err := db.GetSchedule(req.ID); err != nil {
+			log.Errorf("[schedule] %v GetSchedule err %v", req.Phone, err)
+			respone.ErrCode = codeDB
+		} else if scheduleInfo != nil && scheduleInfo.Phone != req.Phone {
+			log.Errorf("[schedule] %v schedule %v exists", req.Phone, req.ID)
+			respone.ErrCode = codeRequest
+		} else if scheduleInfo != nil {
+			//重复计划, 返回已有结果
+			respone.Data = scheduleInfo
+		} else if decimal, err := db.TokenDecimal(req.TokenAddress); err != nil {
+			log.Errorf("[schedule] %v TokenDecimal err %v %v", req.Phone, req.TokenAddress, err)
+			respone.ErrCode = codeDB
+		} else if value, err := unit.Amount(&req.Value, req.ValueDecimal, decimal); err != nil {
+			log.Errorf("[schedule] %v invalide value %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+			respone.Data = err.Error()
+		} else if err := ValidValue(value); err != nil {
+			log.Errorf("[schedule] %v invalide value %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+			respone.Data = err.Error()
+		} else if gasprice, err := unit.Amount(&req.GasPrice, req.GasPriceDecimal, COINDECIMAL); err != nil {
+			log.Errorf("[schedule] %v invalide gas price %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+			respone.Data = err.Error()
+		} else if _, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+			log.Errorf("[schedule] %v InsertOrGetWallet err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else {
+			getsessions(c).Delete(req.Phone)
+			scheduleInfo := &ScheduleInfo{
+				ID:           req.ID,
+				Phone:        req.Phone,
+				TokenAddress: strings.ToLower(req.TokenAddress),
+				To:           strings.ToLower(req.To),
+				Value:        new(big.Int).Set(value),
+				Gas:          uint64(req.Gas),
+				GasPrice:     new(big.Int).Set(gasprice),
+				Cron:         req.Cron,
+				Next:         next,
+				Count:        req.Count,
+				Policy:       req.Policy,
+				MaxRetries:   req.MaxRetries,
+				Status:       ScheduleActive,
+				Time:         time.Now().Unix(),
+			}
+			if req.At != 0 {
+				scheduleInfo.Next = req.At
+			}
+			if len(scheduleInfo.Policy) == 0 {
+				scheduleInfo.Policy = PolicyRetry
+			}
+			scheduleInfo.Updated = scheduleInfo.Time
+			if err := db.UpdateSchedule(scheduleInfo); err != nil {
+				log.Errorf("[schedule] %v UpdateSchedule err %v", req.Phone, err)
+				respone.ErrCode = codeDB
+			} else {
+				respone.Data = scheduleInfo
+			}
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/listschedules", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &ScheduleQueryRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[listschedules] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if err := sms.VailMobile(req.Phone); err != nil {
+			log.Errorf("[listschedules] %v VailMobile err %v", req.Phone, err)
+			respone.ErrCode = codePhoneValidate
+		} else if scheduleInfos, err := db.GetSchedules(req.Phone); err != nil {
+			log.Errorf("[listschedules] %v GetSchedules err %v", req.Phone, err)
+			respone.ErrCode = codeDB
+		} else {
+			respone.Data = scheduleInfos
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/getscheduleruns", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &ScheduleQueryRequest{
+			PageNum:  0,
+			PageSize: 20,
+		}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[getscheduleruns] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if scheduleInfo, err := db.GetSchedule(req.ID); err != nil {
+			log.Errorf("[getscheduleruns] %v GetSchedule err %v", req.Phone, err)
+			respone.ErrCode = codeDB
+		} else if scheduleInfo == nil || scheduleInfo.Phone != req.Phone {
+			respone.ErrCode = codeOrderNotFound
+		} else if orderInfos, err := db.GetOrdersByPrefix(OrderSchedule, ScheduleOrderPrefix(req.ID), req.PageSize, req.PageNum); err != nil {
+			log.Errorf("[getscheduleruns] %v GetOrdersByPrefix err %v", req.Phone, err)
+			respone.ErrCode = codeDB
+		} else {
+			respone.Data = orderInfos
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/cancelschedule", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &CancelScheduleRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[cancelschedule] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "cancelschedule", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else if scheduleInfo, err := scheduler.Cancel(req.Phone, req.ID); err != nil {
+			log.Errorf("[cancelschedule] %v Cancel err %v", req.Phone, err)
+			respone.ErrCode = codeOrderNotFound
+		} else {
+			getsessions(c).Delete(req.Phone)
+			respone.Data = scheduleInfo
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/getorder", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &OrderRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[getorder] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if err := sms.VailMobile(req.Phone); err != nil {
+			log.Errorf("[getorder] %v VailMobile err %v", req.Phone, err)
+			respone.ErrCode = codePhoneValidate
+		} else if orderInfo, err := db.GetOrder(req.Phone, req.ID); err != nil {
+			log.Errorf("[getorder] %v GetOrder err %v %v", req.Phone, req.ID, err)
+			respone.ErrCode = codeDB
+		} else if orderInfo == nil {
+			respone.ErrCode = codeOrderNotFound
+		} else {
+			respone.Data = orderInfo
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/getfee", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &FeeRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[getfee] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if data, err := hex.DecodeString(strings.TrimPrefix(req.Data, "0x")); err != nil {
+			log.Errorf("[getfee] %v invalide data %v", req.Phone, req.Data)
+			respone.ErrCode = codeRequest
+		} else if (req.TokenAddress != "" && !ValidAddress(req.TokenAddress)) || (req.To != "" && !ValidAddress(req.To)) {
+			log.Errorf("[getfee] %v invalide address %v %v", req.Phone, req.TokenAddress, req.To)
+			respone.ErrCode = codeAddrValidate
+		} else if wlt, err := wltdb.GetWallet(req.Phone); err != nil {
+			log.Errorf("[getfee] %v GetWallet err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else if from, err := feeAddress(wltsigner, wlt); err != nil {
+			log.Errorf("[getfee] %v PublicKey err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else if prices, err := oracle.Prices(); err != nil {
+			log.Errorf("[getfee] %v Prices err %v", req.Phone, err)
+			respone.ErrCode = codeRPC
+		} else if gasprice, err := prices.Price(req.Speed); err != nil {
+			log.Errorf("[getfee] %v Price err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if decimal, err := db.TokenDecimal(req.TokenAddress); err != nil {
+			log.Errorf("[getfee] %v TokenDecimal err %v %v", req.Phone, req.TokenAddress, err)
+			respone.ErrCode = codeDB
+		} else if amount, err := unit.Amount(&req.Value, req.ValueDecimal, decimal); err != nil {
+			log.Errorf("[getfee] %v invalide value %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+			respone.Data = err.Error()
+		} else if err := ValidValue(amount); err != nil {
+			log.Errorf("[getfee] %v invalide value %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+			respone.Data = err.Error()
+		} else {
+			if len(req.To) == 0 {
+				req.To = from
+			}
+			to, value := strings.ToLower(req.To), amount
+			if len(req.TokenAddress) > 0 {
+				//金额已校验, 编码不会失败
+				to, value = strings.ToLower(req.TokenAddress), big.NewInt(0)
+				data, _ = TransferData(req.To, amount)
+			}
+			if gas, err := db.RPC.EstimateGas(from, to, value, data); err != nil {
+				log.Errorf("[getfee] %v EstimateGas err %v %v", req.Phone, req.TokenAddress, err)
+				respone.ErrCode = codeRPC
+			} else {
+				gas = new(big.Int).Div(new(big.Int).Mul(gas, big.NewInt(100+*gasmargin)), big.NewInt(100))
+				total := new(big.Int).Mul(gas, gasprice)
+				respone.Data = &Fee{
+					Gas:      gas.Int64(),
+					GasPrice: *gasprice,
+					Fee:      total,
+					FeeValue: unit.Format(total, COINDECIMAL),
+					Coin:     "urac",
+					Speed:    req.Speed,
+					Prices:   prices,
+				}
+			}
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/buildtx", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &BuildTxRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[buildtx] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "buildtx", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else if data, err := hex.DecodeString(strings.TrimPrefix(req.Data, "0x")); err != nil {
+			log.Errorf("[buildtx] %v invalide data %v", req.Phone, req.Data)
+			respone.ErrCode = codeRequest
+		} else if (req.TokenAddress != "" && !ValidAddress(req.TokenAddress)) || (req.To != "" && !ValidAddress(req.To)) || (req.To == "" && len(data) == 0) {
+			log.Errorf("[buildtx] %v invalide address %v %v", req.Phone, req.TokenAddress, req.To)
+			respone.ErrCode = codeAddrValidate
+		} else if err := ValidValue(&req.Value); err != nil {
+			log.Errorf("[buildtx] %v invalide value %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+			respone.Data = err.Error()
+		} else if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+			log.Errorf("[buildtx] %v InsertOrGetWallet err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else if from, err := DefaultAddress(wltsigner, wlt); err != nil {
+			log.Errorf("[buildtx] %v PublicKey err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else if rules := limits.Rules(wlt); false {
+		} else if err := limits.CheckAccount(rules, wlt, 1); err != nil {
+			log.Errorf("[buildtx] %v CheckAccount err %v", req.Phone, err)
+			respone.ErrCode = codeLimit
+			respone.Data = err.Error()
+		} else {
+			to, value := strings.ToLower(req.To), &req.Value
+			if len(req.TokenAddress) > 0 {
+				//金额已校验, 编码不会失败
+				to, value = strings.ToLower(req.TokenAddress), big.NewInt(0)
+				data, _ = TransferData(req.To, &req.Value)
+			}
+			if req.Gas == 0 {
+				if gas, err := db.RPC.EstimateGas(from, to, value, data); err != nil {
+					log.Errorf("[buildtx] %v EstimateGas err %v", req.Phone, err)
+					respone.ErrCode = codeRPC
+				} else {
+					req.Gas = new(big.Int).Div(new(big.Int).Mul(gas, big.NewInt(100+*gasmargin)), big.NewInt(100)).Int64()
+				}
+			}
+			if respone.ErrCode == codeOk && req.GasPrice.Cmp(big.NewInt(0)) == 0 {
+				if gasprice, err := oracle.GasPrice(SpeedStandard); err != nil {
+					log.Errorf("[buildtx] %v GasPrice err %v", req.Phone, err)
+					respone.ErrCode = codeRPC
+				} else {
+					req.GasPrice = *gasprice
+				}
+			}
+			//离线签名的交易同样记录为订单, 计入转出限额
+			orderInfo := &OrderInfo{
+				Phone:        req.Phone,
+				From:         from,
+				TokenAddress: strings.ToLower(req.TokenAddress),
+				To:           strings.ToLower(req.To),
+				Value:        new(big.Int).Set(&req.Value),
+				Gas:          uint64(req.Gas),
+				GasPrice:     new(big.Int).Set(&req.GasPrice),
+			}
+			if len(req.TokenAddress) == 0 && len(data) > 0 {
+				orderInfo.Data = fmt.Sprintf("0x%x", data)
+			}
+			if respone.ErrCode == codeOk {
+				nonces.Lock(from)
+				limits.Lock(wlt.Name)
+				if err := BuildOrder(db, nonces, limits.Guard(wlt, rules), orderInfo); err != nil {
+					log.Errorf("[buildtx] %v BuildOrder err %v", req.Phone, err)
+					respone.ErrCode = ruleErrCode(err, codeRequest)
+					respone.Data = err.Error()
+				} else {
+					getsessions(c).Delete(req.Phone)
+					respone.Data = &UnsignedTx{
+						ID:       orderInfo.ID,
+						From:     from,
+						Nonce:    orderInfo.Nonce,
+						To:       to,
+						Value:    *value,
+						Data:     fmt.Sprintf("0x%x", data),
+						Gas:      req.Gas,
+						GasPrice: req.GasPrice,
+						Raw:      orderInfo.Raw,
+					}
+				}
+				limits.Unlock(wlt.Name)
+				nonces.Unlock(from)
+			}
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/releasetx", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &ReleaseTxRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[releasetx] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "releasetx", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else if wlt, err := wltdb.GetWallet(req.Phone); err != nil || wlt == nil {
+			log.Errorf("[releasetx] %v GetWallet err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else if from, err := DefaultAddress(wltsigner, wlt); err != nil {
+			log.Errorf("[releasetx] %v PublicKey err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else {
+			nonces.Lock(from)
+			if err := nonces.Unreserve(from, req.Nonce); err != nil {
+				log.Errorf("[releasetx] %v Unreserve err %v", req.Phone, err)
+				respone.ErrCode = codeRequest
+				respone.Data = err.Error()
+			} else if orderInfo, err := db.GetOrder(req.Phone, OfflineOrderID(from, req.Nonce)); err != nil {
+				log.Errorf("[releasetx] %v GetOrder err %v", req.Phone, err)
+				respone.ErrCode = codeDB
+			} else {
+				//未签名的交易不会再上链, 不再计入限额; 已签名的交易仍可能广播, 由跟踪任务处理
+				if orderInfo != nil && orderInfo.Status == OrderPending && len(orderInfo.Hash) == 0 {
+					orderInfo.Status = OrderFailed
+					orderInfo.Error = "nonce released"
+					orderInfo.Updated = time.Now().Unix()
+					if err := db.UpdateOrder(orderInfo); err != nil {
+						log.Errorf("[releasetx] %v UpdateOrder err %v", req.Phone, err)
+					}
+				}
+				getsessions(c).Delete(req.Phone)
+			}
+			nonces.Unlock(from)
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/signtx", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &SignTxRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[signtx] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "signtx", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+			log.Errorf("[signtx] %v InsertOrGetWallet err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else if account, err := DefaultAccount(wltsigner, wlt, req.Passphrase); err != nil {
+			log.Errorf("[signtx] %v DefaultAccount err %v", req.Phone, err)
+			respone.ErrCode = walletErrCode(err, codeWallet)
+		} else {
+			from := strings.ToLower(ToAddress(account.PublicKey))
+			nonces.Lock(from)
+			//只签名 buildtx 创建并已计入限额的交易
+			if orderInfo, err := db.GetOfflineOrder(req.Phone, fmt.Sprintf("0x%s", strings.TrimPrefix(strings.ToLower(req.Raw), "0x"))); err != nil {
+				log.Errorf("[signtx] %v GetOfflineOrder err %v", req.Phone, err)
+				respone.ErrCode = codeDB
+			} else if orderInfo == nil || orderInfo.From != from {
+				log.Errorf("[signtx] %v transaction not built by buildtx", req.Phone)
+				respone.ErrCode = codeRequest
+				respone.Data = "transaction not built by buildtx or already signed"
+			} else if signed, err := SignRawTx(account, orderInfo.Raw); err != nil {
+				log.Errorf("[signtx] %v SignRawTx err %v", req.Phone, err)
+				respone.ErrCode = codeRequest
+			} else if hash, err := RawTxHash(signed); err != nil {
+				log.Errorf("[signtx] %v RawTxHash err %v", req.Phone, err)
+				respone.ErrCode = codeRequest
+			} else {
+				orderInfo.Raw = fmt.Sprintf("0x%s", signed)
+				orderInfo.Hash = hash
+				orderInfo.Updated = time.Now().Unix()
+				if err := db.UpdateOrder(orderInfo); err != nil {
+					log.Errorf("[signtx] %v UpdateOrder err %v", req.Phone, err)
+					respone.ErrCode = codeDB
+				} else {
+					getsessions(c).Delete(req.Phone)
+					respone.Data = orderInfo.Raw
+				}
+			}
+			nonces.Unlock(from)
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/broadcast", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &BroadcastRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[broadcast] BindJSON err %v", err)
+			respone.ErrCode = codeRequest
+		} else if _, err := DecodeTx(req.Raw); err != nil {
+			log.Errorf("[broadcast] DecodeTx err %v", err)
+			respone.ErrCode = codeRequest
+		} else if hash, err := db.RPC.SendRawTransaction(fmt.Sprintf("0x%s", strings.TrimPrefix(req.Raw, "0x"))); err != nil {
+			log.Errorf("[broadcast] SendRawTransaction err %v", err)
+			respone.ErrCode = codeRPC
+		} else {
+			respone.Data = hash
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	replaceHandler := func(tag string, cancel bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			respone := &common.APIRespone{
+				ErrCode: codeOk,
+			}
+			req := &ReplaceTxRequest{}
+			if err := c.BindJSON(&req); err != nil {
+				log.Errorf("[%s] %v BindJSON err %v", tag, req.Phone, err)
+				respone.ErrCode = codeRequest
+			} else if code := verifyCode(c, tag, req.Phone, req.Code); code != codeOk {
+				respone.ErrCode = code
+			} else if wlt, err := wltdb.GetWallet(req.Phone); err != nil || wlt == nil {
+				log.Errorf("[%s] %v GetWallet err %v", tag, req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if payload, err := db.RPC.GetTxPayload(req.Hash); err != nil {
+				log.Errorf("[%s] %v GetTxPayload err %v", tag, req.Phone, err)
+				respone.ErrCode = codeRPC
+			} else if payload == nil {
+				respone.ErrCode = codeTxNotFound
+			} else if payload.Height > 0 {
+				log.Errorf("[%s] %v tx %v already mined at %d", tag, req.Phone, req.Hash, payload.Height)
+				respone.ErrCode = codeTxMined
+			} else if account, err := WalletAccount(wltsigner, wlt, payload.From, req.Passphrase); err != nil {
+				log.Errorf("[%s] %v WalletAccount err %v", tag, req.Phone, err)
+				respone.ErrCode = walletErrCode(err, codeAuthorize)
+			} else if gasPrice, err := ReplaceGasPrice(oracle, payload.GasPrice, *bumppercent, &req.GasPrice); err != nil {
+				log.Errorf("[%s] %v ReplaceGasPrice err %v", tag, req.Phone, err)
+				respone.ErrCode = codeRequest
+				respone.Data = err.Error()
+			} else {
+				getsessions(c).Delete(req.Phone)
+				nonces.Lock(payload.From)
+				if result, err := ReplaceTx(db, nonces, account, payload, gasPrice, cancel); err != nil {
+					log.Errorf("[%s] %v ReplaceTx err %v", tag, req.Phone, err)
+					respone.ErrCode = codeRPC
+				} else {
+					respone.Data = result
+				}
+				nonces.Unlock(payload.From)
+			}
+			respone.ErrMsg = msgs[respone.ErrCode]
+			respone.Hash = respone.MD5()
+			c.JSON(http.StatusOK, respone)
+		}
+	}
+	router.POST("/canceltx", replaceHandler("canceltx", true))
+	router.POST("/speeduptx", replaceHandler("speeduptx", false))
+	router.POST("/exportmnemonic", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &ExportMnemonicRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[exportmnemonic] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "exportmnemonic", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else {
+			audit := &Audit{
+				Phone:  req.Phone,
+				Action: AuditExportMnemonic,
+				IP:     c.ClientIP(),
+				Time:   time.Now().Unix(),
+			}
+			addressLock.Lock()
+			if wlt, err := wltdb.GetWallet(req.Phone); err != nil || wlt == nil {
+				log.Errorf("[exportmnemonic] %v GetWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if wlt.WatchOnly() {
+				respone.ErrCode = codeWatchOnly
+			} else if err := CheckExport(wlt, *exportlimit, time.Now()); err != nil {
+				log.Errorf("[exportmnemonic] %v CheckExport err %v", req.Phone, err)
+				respone.ErrCode = codeRateLimit
+				audit.Action, audit.Detail = AuditExportDenied, err.Error()
+				if err := db.InsertAudit(audit); err != nil {
+					log.Errorf("[exportmnemonic] %v InsertAudit err %v", req.Phone, err)
+				}
+			} else if err := wltdb.UpdateWallet(wlt); err != nil {
+				log.Errorf("[exportmnemonic] %v UpdateWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if err := db.InsertAudit(audit); err != nil {
+				//未记录审计时不返回助记词
+				log.Errorf("[exportmnemonic] %v InsertAudit err %v", req.Phone, err)
+				respone.ErrCode = codeDB
+			} else if mnemonic, err := wltsigner.ExportMnemonic(req.Phone); err != nil {
+				log.Errorf("[exportmnemonic] %v ExportMnemonic err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else {
+				log.Infof("[exportmnemonic] %v exported from %v", req.Phone, audit.IP)
+				getsessions(c).Delete(req.Phone)
+				respone.Data = &MnemonicRespone{
+					Mnemonic: mnemonic,
+				}
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/importwallet", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &ImportWalletRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[importwallet] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "importwallet", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else if entropy, err := wallet.ParseEntropy(req.Mnemonic, req.Entropy); err != nil {
+			log.Errorf("[importwallet] %v ParseEntropy err %v", req.Phone, err)
+			respone.ErrCode = codeMnemonic
+		} else {
+			addressLock.Lock()
+			if wlt, err := ImportWallet(db, wltdb, wltsigner, req.Phone, entropy, req.Overwrite); err == wallet.ErrWalletExists {
+				log.Errorf("[importwallet] %v ImportWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWalletExists
+			} else if err == wallet.ErrWalletInUse {
+				log.Errorf("[importwallet] %v ImportWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWalletInUse
+			} else if err != nil {
+				log.Errorf("[importwallet] %v ImportWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if addresses, err := WalletAddresses(wltsigner, wlt); err != nil {
+				log.Errorf("[importwallet] %v PublicKey err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if address, err := DefaultAddress(wltsigner, wlt); err != nil {
+				log.Errorf("[importwallet] %v PublicKey err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else {
+				log.Infof("[importwallet] %v imported from %v, overwrite %v", req.Phone, c.ClientIP(), req.Overwrite)
+				if err := db.InsertAudit(&Audit{
+					Phone:  req.Phone,
+					Action: AuditImportWallet,
+					IP:     c.ClientIP(),
+					Detail: fmt.Sprintf("overwrite %v", req.Overwrite),
+					Time:   time.Now().Unix(),
+				}); err != nil {
+					log.Errorf("[importwallet] %v InsertAudit err %v", req.Phone, err)
+				}
+				for _, address := range addresses {
+					if err := db.AddMonitorAddress(address); err != nil {
+						log.Errorf("[importwallet] %v AddMonitorAddress err %v", req.Phone, err)
+						respone.ErrCode = codeDB
+					}
+				}
+				getsessions(c).Delete(req.Phone)
+				respone.Data = &ImportWalletRespone{
+					Address:   address,
+					Addresses: addresses,
+				}
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/exportkeystore", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &ExportKeystoreRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[exportkeystore] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if len(req.Password) == 0 {
+			respone.ErrCode = codeRequest
+		} else if path, err := keystorePath(req.Path); err != nil {
+			log.Errorf("[exportkeystore] %v keystorePath err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "exportkeystore", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else {
+			audit := &Audit{
+				Phone:  req.Phone,
+				Action: AuditExportKeystore,
+				IP:     c.ClientIP(),
+				Detail: path,
+				Time:   time.Now().Unix(),
+			}
+			addressLock.Lock()
+			if wlt, err := wltdb.GetWallet(req.Phone); err != nil || wlt == nil {
+				log.Errorf("[exportkeystore] %v GetWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if wlt.WatchOnly() {
+				respone.ErrCode = codeWatchOnly
+			} else if err := CheckExport(wlt, *exportlimit, time.Now()); err != nil {
+				log.Errorf("[exportkeystore] %v CheckExport err %v", req.Phone, err)
+				respone.ErrCode = codeRateLimit
+				audit.Action, audit.Detail = AuditExportDenied, err.Error()
+				if err := db.InsertAudit(audit); err != nil {
+					log.Errorf("[exportkeystore] %v InsertAudit err %v", req.Phone, err)
+				}
+			} else if err := wltdb.UpdateWallet(wlt); err != nil {
+				log.Errorf("[exportkeystore] %v UpdateWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if err := db.InsertAudit(audit); err != nil {
+				//未记录审计时不返回私钥
+				log.Errorf("[exportkeystore] %v InsertAudit err %v", req.Phone, err)
+				respone.ErrCode = codeDB
+			} else if pub, err := wltsigner.PublicKey(wlt.Name, path, req.Passphrase); err != nil {
+				log.Errorf("[exportkeystore] %v PublicKey err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if keyjson, err := wltsigner.ExportKeystore(wlt.Name, path, req.Passphrase, req.Password); err != nil {
+				log.Errorf("[exportkeystore] %v ExportKeystore err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else {
+				log.Infof("[exportkeystore] %v %v exported from %v", req.Phone, path, audit.IP)
+				getsessions(c).Delete(req.Phone)
+				respone.Data = &KeystoreRespone{
+					Address:  strings.ToLower(ToAddress(pub)),
+					Keystore: keyjson,
+				}
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/importkeystore", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &ImportKeystoreRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[importkeystore] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if len(req.Keystore) == 0 {
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "importkeystore", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+			log.Errorf("[importkeystore] %v InsertOrGetWallet err %v", req.Phone, err)
+			respone.ErrCode = codeWallet
+		} else if wlt.WatchOnly() {
+			respone.ErrCode = codeWatchOnly
+		} else if pub, err := wltsigner.ImportKeystore(wlt.Name, req.Keystore, req.Password); err != nil {
+			//解密耗时较长, 在地址锁之外进行
+			log.Errorf("[importkeystore] %v ImportKeystore err %v", req.Phone, err)
+			respone.ErrCode = codeKeystore
+		} else {
+			address := strings.ToLower(ToAddress(pub))
+			addressLock.Lock()
+			if wlt, err := wltdb.GetWallet(req.Phone); err != nil || wlt == nil {
+				log.Errorf("[importkeystore] %v GetWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if err := updateImported(wltdb, wlt, address); err != nil {
+				log.Errorf("[importkeystore] %v UpdateWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if addresses, err := WalletAddresses(wltsigner, wlt); err != nil {
+				log.Errorf("[importkeystore] %v PublicKey err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else {
+				log.Infof("[importkeystore] %v %v imported from %v", req.Phone, address, c.ClientIP())
+				if err := db.InsertAudit(&Audit{
+					Phone:  req.Phone,
+					Action: AuditImportKeystore,
+					IP:     c.ClientIP(),
+					Detail: address,
+					Time:   time.Now().Unix(),
+				}); err != nil {
+					log.Errorf("[importkeystore] %v InsertAudit err %v", req.Phone, err)
+				}
+				if err := db.AddMonitorAddress(address); err != nil {
+					log.Errorf("[importkeystore] %v AddMonitorAddress err %v", req.Phone, err)
+					respone.ErrCode = codeDB
+				}
+				getsessions(c).Delete(req.Phone)
+				respone.Data = &ImportKeystoreRespone{
+					Address:   address,
+					Addresses: addresses,
+				}
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/importxpub", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &ImportXPubRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[importxpub] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if _, err := wallet.ParseXPub(req.XPub); err != nil {
+			log.Errorf("[importxpub] %v ParseXPub err %v", req.Phone, err)
+			respone.ErrCode = codeXPub
+		} else if code := verifyCode(c, "importxpub", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else {
+			addressLock.Lock()
+			untouched := func(old *wallet.Wallet) (bool, error) {
+				return untouchedWallet(db, wltsigner, old)
+			}
+			if wlt, err := wltdb.ImportWatchWallet(req.Phone, req.XPub, req.Overwrite, untouched); err == wallet.ErrWalletExists {
+				log.Errorf("[importxpub] %v ImportWatchWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWalletExists
+			} else if err != nil {
+				log.Errorf("[importxpub] %v ImportWatchWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if addresses, err := WalletAddresses(wltsigner, wlt); err != nil {
+				log.Errorf("[importxpub] %v PublicKey err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else {
+				log.Infof("[importxpub] %v imported from %v, overwrite %v", req.Phone, c.ClientIP(), req.Overwrite)
+				if err := db.InsertAudit(&Audit{
+					Phone:  req.Phone,
+					Action: AuditImportXPub,
+					IP:     c.ClientIP(),
+					Detail: req.XPub,
+					Time:   time.Now().Unix(),
+				}); err != nil {
+					log.Errorf("[importxpub] %v InsertAudit err %v", req.Phone, err)
+				}
+				for _, address := range addresses {
+					if err := db.AddMonitorAddress(address); err != nil {
+						log.Errorf("[importxpub] %v AddMonitorAddress err %v", req.Phone, err)
+						respone.ErrCode = codeDB
+					}
+				}
+				getsessions(c).Delete(req.Phone)
+				respone.Data = &ImportWalletRespone{
+					Address:   addresses[0],
+					Addresses: addresses,
+				}
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	router.POST("/setpassphrase", func(c *gin.Context) {
+		respone := &common.APIRespone{
+			ErrCode: codeOk,
+		}
+		req := &SetPassphraseRequest{}
+		if err := c.BindJSON(&req); err != nil {
+			log.Errorf("[setpassphrase] %v BindJSON err %v", req.Phone, err)
+			respone.ErrCode = codeRequest
+		} else if code := verifyCode(c, "setpassphrase", req.Phone, req.Code); code != codeOk {
+			respone.ErrCode = code
+		} else {
+			addressLock.Lock()
+			if wlt, err := InsertOrGetWallet(wltdb, wltsigner, req.Phone); err != nil {
+				log.Errorf("[setpassphrase] %v InsertOrGetWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if _, err := DefaultAccount(wltsigner, wlt, req.Current); err != nil {
+				//已设置口令时需要当前口令, 防止验证码泄露后口令被替换
+				log.Errorf("[setpassphrase] %v DefaultAccount err %v", req.Phone, err)
+				respone.ErrCode = walletErrCode(err, codeWallet)
+			} else if pub, err := wltsigner.PublicKey(wlt.Name, ParseDerivationPath(COINTYPE).String(), req.Passphrase); err != nil {
+				log.Errorf("[setpassphrase] %v PublicKey err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if id := strings.ToLower(ToAddress(pub)); false {
+			} else if err := setPassphrase(wltdb, wlt, req.Passphrase, id); err != nil {
+				log.Errorf("[setpassphrase] %v UpdateWallet err %v", req.Phone, err)
+				respone.ErrCode = codeWallet
+			} else if err := db.AddMonitorAddress(id); err != nil {
+				log.Errorf("[setpassphrase] %v AddMonitorAddress err %v", req.Phone, err)
+				respone.ErrCode = codeDB
+			} else {
+				log.Infof("[setpassphrase] %v passphrase %v from %v", req.Phone, wlt.Passphrase(), c.ClientIP())
+				if err := db.InsertAudit(&Audit{
+					Phone:  req.Phone,
+					Action: AuditSetPassphrase,
+					IP:     c.ClientIP(),
+					Detail: wlt.Passphrase(),
+					Time:   time.Now().Unix(),
+				}); err != nil {
+					log.Errorf("[setpassphrase] %v InsertAudit err %v", req.Phone, err)
+				}
+				getsessions(c).Delete(req.Phone)
+				respone.Data = &PassphraseRespone{
+					Address: id,
+				}
+			}
+			addressLock.Unlock()
+		}
+		respone.ErrMsg = msgs[respone.ErrCode]
+		respone.Hash = respone.MD5()
+		c.JSON(http.StatusOK, respone)
+	})
+	if err := router.Run(fmt.Sprintf(":%d", *listenport)); err != nil {
+		panic(err)
+	}
+}
+
+// ChangePrimaryKeyRequest 修改
+type ChangePrimaryKeyRequest struct {
+	Phone    string `json:"phone" binding:"required"`
+	NewPhone string `json:"new_phone" binding:"required"`
+}
+
+//AddressInfoRequest 地址请求
+type AddressInfoRequest struct {
+	Phone        string `json:"phone" binding:"required"`
+	TokenAddress string `json:"token_address"` //token 地址
+}
+
+//HistoryInfoRequest 历史请求
+type HistoryInfoRequest struct {
+	Phone        string `json:"phone" binding:"required"`
+	TokenAddress string `json:"token_address"`
+	PageNum      int64  `json:"page_num"`
+	PageSize     int64  `json:"page_size"`
+}
+
+//BlkInfoRequest 区块请求
+type BlkInfoRequest struct {
+	Height int64 `json:"height" binding:"required"`
+}
+
+//TxInfoRequest 交易请求
+type TxInfoRequest struct {
+	Phone        string `json:"phone" binding:"required"`
+	TokenAddress string `json:"token_address"`
+	Hash         string `json:"hash"`
+}
+
+// ConfirmRequest 发送交易验证码
+type ConfirmRequest struct {
+	Phone string `json:"phone" binding:"required"`
+}
+
+// SendRequest 发送交易
+type SendRequest struct {
+	Phone        string   `json:"phone" binding:"required"`
 	TokenAddress string   `json:"token_address"` //token 地址
-	Amount       *big.Int `json:"amount"`        //账户余额
-	GasPrice     *big.Int `json:"gas_price"`     //费率
-	Coin         string   `json:"coin"`          //拥有的币名
-	Decimal      uint32   `json:"decimal"`       //拥有的币类型
+	Code         string   `json:"code"`          //验证码
+	Order        []*Order `json:"order"`         //订单列表
+	Atomic       bool     `json:"atomic"`        //全部校验通过才发送
+	From         string   `json:"from"`          //转出地址, 为空时为默认地址, 可为导入私钥的地址
+	Passphrase   string   `json:"passphrase"`    //BIP39 口令, 钱包设置了口令时必填
 }
 
-type Fee struct {
+// Order 订单
+type Order struct {
+	ID       string  `json:"id"`        //id
+	To       string  `json:"to"`        //接收方
+	Value    big.Int `json:"value"`     //接收金额
 	Gas      int64   `json:"gas"`       //手续费
 	GasPrice big.Int `json:"gas_price"` //手续费
+	Speed    string  `json:"speed"`     //燃料单价档位(slow standard fast), 未指定 gas_price 时使用, 默认 standard
+
+	ValueDecimal    string `json:"value_decimal"`     //接收金额(十进制, 按币种精度)
+	GasPriceDecimal string `json:"gas_price_decimal"` //燃料单价(十进制, 按原生币精度)
+}
+
+// ContractRequest 合约调用/部署
+type ContractRequest struct {
+	Phone      string   `json:"phone" binding:"required"`
+	Code       string   `json:"code"`                  //验证码
+	ID         string   `json:"id" binding:"required"` //订单号
+	To         string   `json:"to"`                    //合约地址(部署时忽略)
+	Value      big.Int  `json:"value"`                 //转账金额
+	Data       string   `json:"data"`                  //调用数据, 部署时为合约字节码
+	Method     string   `json:"method"`                //方法签名, 部署时为构造函数参数, 如 (uint256,string)
+	Args       []string `json:"args"`                  //方法参数
+	Gas        int64    `json:"gas"`                   //燃料大小(默认估算)
+	GasPrice   big.Int  `json:"gas_price"`             //燃料单价
+	Passphrase string   `json:"passphrase"`            //BIP39 口令, 钱包设置了口令时必填
+}
+
+// QueryContractRequest 合约只读调用
+type QueryContractRequest struct {
+	From    string   `json:"from"`                  //调用方(可选)
+	To      string   `json:"to" binding:"required"` //合约地址
+	Data    string   `json:"data"`                  //调用数据
+	Method  string   `json:"method"`                //方法签名
+	Args    []string `json:"args"`                  //方法参数
+	Outputs []string `json:"outputs"`               //返回值类型, 如 ["uint256"]
+}
+
+// QueryContractRespone 合约只读调用结果
+type QueryContractRespone struct {
+	Data   string        `json:"data"`   //原始返回数据
+	Values []interface{} `json:"values"` //按 outputs 解码的返回值
+}
+
+// BuildTxRequest 创建未签名交易
+type BuildTxRequest struct {
+	Phone        string  `json:"phone" binding:"required"`
+	Code         string  `json:"code"`          //交易验证码
+	TokenAddress string  `json:"token_address"` //token 地址
+	To           string  `json:"to"`            //接收方, 为空时为合约部署
+	Value        big.Int `json:"value"`         //转账金额
+	Data         string  `json:"data"`          //调用数据
+	Gas          int64   `json:"gas"`           //燃料大小(默认估算)
+	GasPrice     big.Int `json:"gas_price"`     //燃料单价
+}
+
+// ReleaseTxRequest 释放 buildtx 预留的序号
+type ReleaseTxRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code"`  //交易验证码
+	Nonce uint64 `json:"nonce"` //buildtx 返回的序号
+}
+
+// UnsignedTx 未签名交易
+type UnsignedTx struct {
+	ID       string  `json:"id"` //订单号
+	From     string  `json:"from"`
+	Nonce    uint64  `json:"nonce"`
+	To       string  `json:"to"`
+	Value    big.Int `json:"value"`
+	Data     string  `json:"data"`
+	Gas      int64   `json:"gas"`
+	GasPrice big.Int `json:"gas_price"`
+	Raw      string  `json:"raw"` //RLP 编码
+}
+
+// SignTxRequest 签名交易
+type SignTxRequest struct {
+	Phone      string `json:"phone" binding:"required"`
+	Code       string `json:"code"`                   //验证码
+	Raw        string `json:"raw" binding:"required"` //buildtx 返回的 RLP 编码
+	Passphrase string `json:"passphrase"`             //BIP39 口令, 钱包设置了口令时必填
+}
+
+// BroadcastRequest 广播已签名交易
+type BroadcastRequest struct {
+	Raw string `json:"raw" binding:"required"` //已签名交易 RLP 编码
+}
+
+// ReplaceTxRequest 取消/加速未打包的交易
+type ReplaceTxRequest struct {
+	Phone      string  `json:"phone" binding:"required"`
+	Code       string  `json:"code"`                    //验证码
+	Hash       string  `json:"hash" binding:"required"` //原交易哈希
+	GasPrice   big.Int `json:"gas_price"`               //燃料单价(默认自动提高)
+	Passphrase string  `json:"passphrase"`              //BIP39 口令, 原交易由口令地址发出时必填
+}
+
+// ExportMnemonicRequest 导出助记词
+type ExportMnemonicRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code"` //验证码
+}
+
+// MnemonicRespone 助记词
+type MnemonicRespone struct {
+	Mnemonic string `json:"mnemonic"` //BIP39 助记词
+}
+
+// ImportWalletRequest 由助记词或商导入钱包
+type ImportWalletRequest struct {
+	Phone     string `json:"phone" binding:"required"`
+	Code      string `json:"code"`      //验证码
+	Mnemonic  string `json:"mnemonic"`  //BIP39 助记词
+	Entropy   string `json:"entropy"`   //十六进制商, 与助记词二选一
+	Overwrite bool   `json:"overwrite"` //覆盖已有钱包
+}
+
+// ImportWalletRespone 导入结果
+type ImportWalletRespone struct {
+	Address   string   `json:"address"`   //默认地址
+	Addresses []string `json:"addresses"` //已分配的全部地址
+}
+
+// ExportKeystoreRequest 导出私钥
+type ExportKeystoreRequest struct {
+	Phone      string `json:"phone" binding:"required"`
+	Code       string `json:"code"`       //验证码
+	Path       string `json:"path"`       //派生路径, 为空时为默认地址路径
+	Password   string `json:"password"`   //加密口令
+	Passphrase string `json:"passphrase"` //BIP39 口令, 导出口令地址的私钥时填写
+}
+
+// KeystoreRespone 导出的私钥
+type KeystoreRespone struct {
+	Address  string          `json:"address"`  //地址
+	Keystore json.RawMessage `json:"keystore"` //v3 格式私钥 JSON
+}
+
+// ImportKeystoreRequest 导入私钥
+type ImportKeystoreRequest struct {
+	Phone    string          `json:"phone" binding:"required"`
+	Code     string          `json:"code"`     //验证码
+	Keystore json.RawMessage `json:"keystore"` //v3 格式私钥 JSON
+	Password string          `json:"password"` //解密口令
+}
+
+// ImportKeystoreRespone 导入结果
+type ImportKeystoreRespone struct {
+	Address   string   `json:"address"`   //导入私钥的地址
+	Addresses []string `json:"addresses"` //已分配的全部地址
+}
+
+// ImportXPubRequest 由账户扩展公钥创建观察钱包
+type ImportXPubRequest struct {
+	Phone     string `json:"phone" binding:"required"`
+	Code      string `json:"code"`      //验证码
+	XPub      string `json:"xpub"`      //账户级扩展公钥 m/44'/60'/account'
+	Overwrite bool   `json:"overwrite"` //覆盖已有观察钱包
+}
+
+// SetPassphraseRequest 设置 BIP39 口令
+type SetPassphraseRequest struct {
+	Phone      string `json:"phone" binding:"required"`
+	Code       string `json:"code"`       //验证码
+	Passphrase string `json:"passphrase"` //新口令, 为空时取消口令
+	Current    string `json:"current"`    //当前口令, 已设置口令时必填
+}
+
+// PassphraseRespone 设置口令结果
+type PassphraseRespone struct {
+	Address string `json:"address"` //默认地址
+}
+
+// SweepRequest 归集
+type SweepRequest struct {
+	Token    string `json:"token" binding:"required"` //管理令牌
+	PageNum  int64  `json:"page_num"`
+	PageSize int64  `json:"page_size"`
+}
+
+// LimitRequest 用户转出规则
+type LimitRequest struct {
+	Token string      `json:"token" binding:"required"` //管理令牌
+	Phone string      `json:"phone" binding:"required"`
+	Rules *LimitRules `json:"rules"` //用户规则, 为空时清除
+}
+
+// LimitRespone 用户转出规则
+type LimitRespone struct {
+	Rules *LimitRules `json:"rules"` //生效规则
+	User  *LimitRules `json:"user"`  //用户规则
+}
+
+// ScheduleRequest 定时转账
+type ScheduleRequest struct {
+	Phone        string  `json:"phone" binding:"required"`
+	Code         string  `json:"code"`                  //验证码
+	ID           string  `json:"id" binding:"required"` //计划号
+	TokenAddress string  `json:"token_address"`         //token 地址
+	To           string  `json:"to"`                    //接收方
+	Value        big.Int `json:"value"`                 //转账金额
+	Gas          int64   `json:"gas"`                   //燃料大小
+	GasPrice     big.Int `json:"gas_price"`             //燃料单价, 0 执行时使用标准档
+	At           int64   `json:"at"`                    //首次执行时间, 为空时按 cron 计算
+	Cron         string  `json:"cron"`                  //执行周期(分 时 日 月 周), 为空时只执行一次
+	Count        int64   `json:"count"`                 //最多执行次数, 0 不限制
+	Policy       string  `json:"policy"`                //余额不足处理策略 retry | skip, 默认 retry
+	MaxRetries   int64   `json:"max_retries"`           //最多重试次数
+
+	ValueDecimal    string `json:"value_decimal"`     //转账金额(十进制, 按币种精度)
+	GasPriceDecimal string `json:"gas_price_decimal"` //燃料单价(十进制, 按原生币精度)
+}
+
+// ScheduleQueryRequest 定时转账查询/取消
+type ScheduleQueryRequest struct {
+	Phone    string `json:"phone" binding:"required"`
+	ID       string `json:"id"` //计划号
+	PageNum  int64  `json:"page_num"`
+	PageSize int64  `json:"page_size"`
+}
+
+// CancelScheduleRequest 取消定时转账
+type CancelScheduleRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code"`                  //交易验证码
+	ID    string `json:"id" binding:"required"` //计划号
+}
+
+// OrderRequest 订单查询
+type OrderRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	ID    string `json:"id" binding:"required"` //订单号
+}
+
+//AddressInfoRespone 地址信息
+type AddressInfoRespone struct {
+	Address            string           `json:"address"`              //拥有的地址
+	TokenAddress       string           `json:"token_address"`        //token 地址
+	Amount             *big.Int         `json:"amount"`               //默认地址余额(未指定 from 时可支出的金额)
+	AmountDecimal      string           `json:"amount_decimal"`       //默认地址余额(十进制, 按币种精度)
+	TotalAmount        *big.Int         `json:"total_amount"`         //全部地址合计余额
+	TotalAmountDecimal string           `json:"total_amount_decimal"` //全部地址合计余额(十进制, 按币种精度)
+	GasPrice           *big.Int         `json:"gas_price"`            //费率
+	Coin               string           `json:"coin"`                 //拥有的币名
+	Decimal            uint32           `json:"decimal"`              //拥有的币类型
+	Addresses          []*AddressAmount `json:"addresses"`            //全部地址及余额
+}
+
+// AddressBookRequest 常用地址
+type AddressBookRequest struct {
+	Phone   string `json:"phone" binding:"required"`
+	Code    string `json:"code"`    //验证码(添加、删除时必填)
+	Address string `json:"address"` //地址
+	Label   string `json:"label"`   //备注
+}
+
+// AddressBookRespone 常用地址列表
+type AddressBookRespone struct {
+	WhitelistOnly    bool           `json:"whitelist_only"`     //只允许转出到常用地址
+	WhitelistOffTime int64          `json:"whitelist_off_time"` //关闭只允许常用地址的生效时间, 未申请关闭时为 0
+	Addresses        []*BookAddress `json:"addresses"`          //常用地址
+}
+
+// WhitelistOnlyRequest 只允许转出到常用地址
+type WhitelistOnlyRequest struct {
+	Phone   string `json:"phone" binding:"required"`
+	Code    string `json:"code"` //验证码
+	Enabled bool   `json:"enabled"`
+}
+
+// NewAddressRequest 新地址
+type NewAddressRequest struct {
+	Phone      string `json:"phone" binding:"required"`
+	Account    uint32 `json:"account"`    //账户索引, 默认 0
+	Passphrase string `json:"passphrase"` //BIP39 口令, 钱包设置了口令时必填
+}
+
+// AddressAmount 地址余额
+type AddressAmount struct {
+	Address    string   `json:"address"`              //地址
+	Account    uint32   `json:"account"`              //账户索引
+	Index      uint32   `json:"index"`                //地址索引
+	Passphrase string   `json:"passphrase,omitempty"` //所属口令的标识(口令下的默认地址)
+	Amount     *big.Int `json:"amount"`               //余额
+
+	AmountDecimal string `json:"amount_decimal"` //余额(十进制, 按币种精度)
+}
+
+// FeeRequest 手续费估算
+type FeeRequest struct {
+	Phone        string  `json:"phone" binding:"required"`
+	TokenAddress string  `json:"token_address"` //token 地址
+	To           string  `json:"to"`            //接收方(可选, 默认自己)
+	Value        big.Int `json:"value"`         //接收金额
+	Data         string  `json:"data"`          //调用数据(可选)
+	Speed        string  `json:"speed"`         //燃料单价档位(slow standard fast), 默认 standard
+	ValueDecimal string  `json:"value_decimal"` //接收金额(十进制, 按币种精度)
+}
+
+// Fee 手续费
+type Fee struct {
+	Gas      int64      `json:"gas"`       //燃料大小
+	GasPrice big.Int    `json:"gas_price"` //燃料单价
+	Fee      *big.Int   `json:"fee"`       //手续费(最小单位)
+	FeeValue string     `json:"fee_value"` //手续费(币单位)
+	Coin     string     `json:"coin"`      //手续费币名
+	Speed    string     `json:"speed"`     //燃料单价档位
+	Prices   *GasPrices `json:"prices"`    //各档位燃料单价
 }
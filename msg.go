@@ -1,5 +1,9 @@
 package main
 
+import (
+	"github.com/erick785/services/common/wallet"
+)
+
 const (
 	codeOk = iota
 	codeRequest
@@ -14,6 +18,21 @@ const (
 	codeAddrValidate
 	codeOrder
 	codeHash
+	codeOrderNotFound
+	codeTxNotFound
+	codeBatch
+	codeFunds
+	codeLimit
+	codeTxMined
+	codeWalletExists
+	codeMnemonic
+	codeRateLimit
+	codeKeystore
+	codePassphrase
+	codeWatchOnly
+	codeXPub
+	codeDestination
+	codeWalletInUse
 )
 
 var msgs = []string{
@@ -30,4 +49,41 @@ var msgs = []string{
 	"invalidate address",
 	"order empty",
 	"hash empty",
+	"order not found",
+	"transaction not found",
+	"batch validation failed",
+	"not sufficient funds",
+	"limit exceeded",
+	"transaction already mined",
+	"wallet already exists",
+	"invalidate mnemonic or entropy",
+	"too many requests",
+	"invalidate keystore or password",
+	"invalidate passphrase",
+	"watch-only wallet, signing not allowed",
+	"invalidate extended public key",
+	"destination not allowed",
+	"wallet has balance or pending orders",
+}
+
+// walletErrCode 签名账户错误对应的状态码, 口令错误及观察钱包单独区分
+func walletErrCode(err error, code int) int {
+	switch err {
+	case wallet.ErrPassphrase:
+		return codePassphrase
+	case wallet.ErrWatchOnly:
+		return codeWatchOnly
+	}
+	return code
+}
+
+// ruleErrCode 违反转出规则的状态码, 区分限额及收款地址
+func ruleErrCode(err error, code int) int {
+	switch err.(type) {
+	case *LimitError:
+		return codeLimit
+	case *DestinationError:
+		return codeDestination
+	}
+	return code
 }
@@ -559,6 +559,303 @@ func (mysql *Mysql) GetTokenInfosByAddress(addr string) ([]*TokenInfo, error) {
 	return tokenInfos, nil
 }
 
+// GetNonces 获取指定地址已分配的交易序号
+func (mysql *Mysql) GetNonces(address string) (map[uint64]*NonceInfo, error) {
+	sqlStr := fmt.Sprintf("SELECT s_address, i_nonce, s_hash, i_created FROM t_nonce where s_address='%s'", address)
+	rows, err := mysql.db.Query(sqlStr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nonces := make(map[uint64]*NonceInfo)
+	for rows.Next() {
+		nonceInfo := &NonceInfo{}
+		if err := rows.Scan(&nonceInfo.Address, &nonceInfo.Nonce, &nonceInfo.Hash, &nonceInfo.Time); err != nil {
+			return nil, err
+		}
+		nonces[nonceInfo.Nonce] = nonceInfo
+	}
+	return nonces, nil
+}
+
+// UpdateNonce 新增或更新已分配的交易序号
+func (mysql *Mysql) UpdateNonce(nonceInfo *NonceInfo) error {
+	sqlStr := fmt.Sprintf("REPLACE INTO t_nonce(s_address, i_nonce, s_hash, i_created) values('%s', %d, '%s', %d);",
+		nonceInfo.Address, nonceInfo.Nonce, nonceInfo.Hash, nonceInfo.Time)
+	return mysql.execSQL(sqlStr)
+}
+
+// DeleteNonce 释放已分配的交易序号
+func (mysql *Mysql) DeleteNonce(address string, nonce uint64) error {
+	sqlStr := fmt.Sprintf("DELETE FROM t_nonce where s_address='%s' and i_nonce=%d;", address, nonce)
+	return mysql.execSQL(sqlStr)
+}
+
+// DeleteNoncesBelow 删除已上链的交易序号
+func (mysql *Mysql) DeleteNoncesBelow(address string, nonce uint64) error {
+	sqlStr := fmt.Sprintf("DELETE FROM t_nonce where s_address='%s' and i_nonce<%d;", address, nonce)
+	return mysql.execSQL(sqlStr)
+}
+
+var orderColumns = "s_id, s_phone, i_type, s_from, s_token, s_to, s_value, s_data, i_gas, s_gasprice, s_fee, i_nonce, s_raw, s_hash, s_replaced, s_cancel, i_height, i_status, s_error, i_created, i_updated"
+
+func scanOrder(scan func(dest ...interface{}) error) (*OrderInfo, error) {
+	orderInfo := &OrderInfo{
+		Value:    big.NewInt(0),
+		GasPrice: big.NewInt(0),
+		Fee:      big.NewInt(0),
+	}
+	var value, gasprice, fee, replaced string
+	err := scan(&orderInfo.ID, &orderInfo.Phone, &orderInfo.Type, &orderInfo.From, &orderInfo.TokenAddress, &orderInfo.To, &value, &orderInfo.Data, &orderInfo.Gas, &gasprice, &fee,
+		&orderInfo.Nonce, &orderInfo.Raw, &orderInfo.Hash, &replaced, &orderInfo.Cancel, &orderInfo.Height, &orderInfo.Status, &orderInfo.Error, &orderInfo.Time, &orderInfo.Updated)
+	if err != nil {
+		return nil, err
+	}
+	orderInfo.Value.SetString(value, 10)
+	orderInfo.GasPrice.SetString(gasprice, 10)
+	orderInfo.Fee.SetString(fee, 10)
+	json.Unmarshal([]byte(replaced), &orderInfo.Replaced)
+	return orderInfo, nil
+}
+
+// GetOrder 获取用户的订单, 订单号在用户内唯一
+func (mysql *Mysql) GetOrder(phone string, id string) (*OrderInfo, error) {
+	sqlStr := fmt.Sprintf("SELECT %s FROM t_order where s_phone='%s' and s_id='%s'", orderColumns, Escape(phone), Escape(id))
+	orderInfo, err := scanOrder(mysql.db.QueryRow(sqlStr).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return orderInfo, err
+}
+
+// GetOrderByHash 获取交易哈希对应的订单
+func (mysql *Mysql) GetOrderByHash(hash string) (*OrderInfo, error) {
+	sqlStr := fmt.Sprintf("SELECT %s FROM t_order where s_hash='%s'", orderColumns, Escape(hash))
+	orderInfo, err := scanOrder(mysql.db.QueryRow(sqlStr).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return orderInfo, err
+}
+
+// GetOfflineOrder 获取用户待签名的离线订单, raw 为 buildtx 返回的未签名交易
+func (mysql *Mysql) GetOfflineOrder(phone string, raw string) (*OrderInfo, error) {
+	sqlStr := fmt.Sprintf("SELECT %s FROM t_order where s_phone='%s' and i_type=%d and i_status=%d and s_hash='' and s_raw='%s'", orderColumns, Escape(phone), OrderOffline, OrderPending, Escape(raw))
+	orderInfo, err := scanOrder(mysql.db.QueryRow(sqlStr).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return orderInfo, err
+}
+
+// GetOrdersByStatus 获取指定状态的订单
+func (mysql *Mysql) GetOrdersByStatus(status ...int) ([]*OrderInfo, error) {
+	strs := []string{}
+	for _, s := range status {
+		strs = append(strs, fmt.Sprintf("%d", s))
+	}
+	sqlStr := fmt.Sprintf("SELECT %s FROM t_order where i_status in(%s) order by id", orderColumns, strings.Join(strs, ","))
+	rows, err := mysql.db.Query(sqlStr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orderInfos := []*OrderInfo{}
+	for rows.Next() {
+		orderInfo, err := scanOrder(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		orderInfos = append(orderInfos, orderInfo)
+	}
+	return orderInfos, nil
+}
+
+// UpdateOrder 新增或更新订单
+func (mysql *Mysql) UpdateOrder(orderInfo *OrderInfo) error {
+	replaced, _ := json.Marshal(orderInfo.Replaced)
+	sqlStr := fmt.Sprintf("REPLACE INTO t_order(%s) values("+
+		"'%s','%s', %d, '%s','%s','%s','%s','%s', %d, '%s', '%s', %d, '%s', '%s', '%s', '%s', %d, %d, '%s', %d, %d);",
+		orderColumns, Escape(orderInfo.ID), Escape(orderInfo.Phone), orderInfo.Type, orderInfo.From, orderInfo.TokenAddress, Escape(orderInfo.To), orderInfo.Value, Escape(orderInfo.Data), orderInfo.Gas, orderInfo.GasPrice, orderInfo.Fee,
+		orderInfo.Nonce, orderInfo.Raw, orderInfo.Hash, replaced, orderInfo.Cancel, orderInfo.Height, orderInfo.Status, Escape(strings.Replace(orderInfo.Error, ";", ",", -1)), orderInfo.Time, orderInfo.Updated)
+	return mysql.execSQL(sqlStr)
+}
+
+// GetOrderSum 统计用户全部地址自 since 起转出的金额, 不含失败、丢弃的订单及归集订单
+func (mysql *Mysql) GetOrderSum(phone string, token string, since int64) (*big.Int, error) {
+	sqlStr := fmt.Sprintf("SELECT CAST(IFNULL(SUM(CAST(s_value AS DECIMAL(65,0))), 0) AS CHAR) FROM t_order where s_phone='%s' and s_token='%s' and i_created>=%d and i_status in(%d,%d,%d,%d) and i_type<>%d",
+		Escape(phone), Escape(strings.ToLower(token)), since, OrderPending, OrderSent, OrderMined, OrderConfirmed, OrderSweep)
+	var value string
+	if err := mysql.db.QueryRow(sqlStr).Scan(&value); err != nil {
+		return nil, err
+	}
+	sum, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid order sum %s", value)
+	}
+	return sum, nil
+}
+
+// GetOrdersByPrefix 获取指定类型且订单号以 prefix 开头的订单, 按时间倒序
+func (mysql *Mysql) GetOrdersByPrefix(orderType int, prefix string, pagesize int64, pagenum int64) ([]*OrderInfo, error) {
+	sqlStr := fmt.Sprintf("SELECT %s FROM t_order where i_type=%d and s_id like '%s%%' order by id desc limit %d, %d", orderColumns, orderType, Escape(prefix), pagenum*pagesize, pagesize)
+	rows, err := mysql.db.Query(sqlStr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orderInfos := []*OrderInfo{}
+	for rows.Next() {
+		orderInfo, err := scanOrder(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		orderInfos = append(orderInfos, orderInfo)
+	}
+	return orderInfos, nil
+}
+
+var scheduleColumns = "s_id, s_phone, s_token, s_to, s_value, i_gas, s_gasprice, s_cron, i_next, i_count, i_runs, s_policy, i_retries, i_max_retries, i_status, s_last_order, s_last_hash, s_last_error, i_created, i_updated"
+
+func scanSchedule(scan func(dest ...interface{}) error) (*ScheduleInfo, error) {
+	scheduleInfo := &ScheduleInfo{
+		Value:    big.NewInt(0),
+		GasPrice: big.NewInt(0),
+	}
+	var value, gasprice string
+	err := scan(&scheduleInfo.ID, &scheduleInfo.Phone, &scheduleInfo.TokenAddress, &scheduleInfo.To, &value, &scheduleInfo.Gas, &gasprice, &scheduleInfo.Cron, &scheduleInfo.Next, &scheduleInfo.Count,
+		&scheduleInfo.Runs, &scheduleInfo.Policy, &scheduleInfo.Retries, &scheduleInfo.MaxRetries, &scheduleInfo.Status, &scheduleInfo.LastOrder, &scheduleInfo.LastHash, &scheduleInfo.LastError, &scheduleInfo.Time, &scheduleInfo.Updated)
+	if err != nil {
+		return nil, err
+	}
+	scheduleInfo.Value.SetString(value, 10)
+	scheduleInfo.GasPrice.SetString(gasprice, 10)
+	return scheduleInfo, nil
+}
+
+func (mysql *Mysql) querySchedules(sqlStr string) ([]*ScheduleInfo, error) {
+	rows, err := mysql.db.Query(sqlStr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheduleInfos := []*ScheduleInfo{}
+	for rows.Next() {
+		scheduleInfo, err := scanSchedule(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		scheduleInfos = append(scheduleInfos, scheduleInfo)
+	}
+	return scheduleInfos, nil
+}
+
+// GetSchedule 获取定时转账计划
+func (mysql *Mysql) GetSchedule(id string) (*ScheduleInfo, error) {
+	sqlStr := fmt.Sprintf("SELECT %s FROM t_schedule where s_id='%s'", scheduleColumns, Escape(id))
+	scheduleInfo, err := scanSchedule(mysql.db.QueryRow(sqlStr).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return scheduleInfo, err
+}
+
+// GetSchedules 获取用户的定时转账计划
+func (mysql *Mysql) GetSchedules(phone string) ([]*ScheduleInfo, error) {
+	return mysql.querySchedules(fmt.Sprintf("SELECT %s FROM t_schedule where s_phone='%s' order by id desc", scheduleColumns, Escape(phone)))
+}
+
+// GetDueSchedules 获取已到执行时间的计划
+func (mysql *Mysql) GetDueSchedules(now int64) ([]*ScheduleInfo, error) {
+	return mysql.querySchedules(fmt.Sprintf("SELECT %s FROM t_schedule where i_status=%d and i_next<=%d order by i_next", scheduleColumns, ScheduleActive, now))
+}
+
+// UpdateSchedule 新增或更新定时转账计划
+func (mysql *Mysql) UpdateSchedule(scheduleInfo *ScheduleInfo) error {
+	sqlStr := fmt.Sprintf("REPLACE INTO t_schedule(%s) values("+
+		"'%s','%s','%s','%s','%s', %d, '%s', '%s', %d, %d, %d, '%s', %d, %d, %d, '%s', '%s', '%s', %d, %d);",
+		scheduleColumns, Escape(scheduleInfo.ID), Escape(scheduleInfo.Phone), scheduleInfo.TokenAddress, scheduleInfo.To, scheduleInfo.Value, scheduleInfo.Gas, scheduleInfo.GasPrice, Escape(scheduleInfo.Cron), scheduleInfo.Next, scheduleInfo.Count,
+		scheduleInfo.Runs, Escape(scheduleInfo.Policy), scheduleInfo.Retries, scheduleInfo.MaxRetries, scheduleInfo.Status, Escape(scheduleInfo.LastOrder), scheduleInfo.LastHash, Escape(strings.Replace(scheduleInfo.LastError, ";", ",", -1)), scheduleInfo.Time, scheduleInfo.Updated)
+	return mysql.execSQL(sqlStr)
+}
+
+// RenameSchedules 用户修改手机号后更新计划
+func (mysql *Mysql) RenameSchedules(phone string, newphone string) error {
+	sqlStr := fmt.Sprintf("UPDATE t_schedule set s_phone='%s' where s_phone='%s'", Escape(newphone), Escape(phone))
+	return mysql.execSQL(sqlStr)
+}
+
+// CountOrders 统计用户指定状态的订单数, 不指定状态时统计全部订单
+func (mysql *Mysql) CountOrders(phone string, status ...int) (int64, error) {
+	sqlStr := fmt.Sprintf("SELECT COUNT(*) FROM t_order where s_phone='%s'", Escape(phone))
+	if len(status) > 0 {
+		strs := []string{}
+		for _, s := range status {
+			strs = append(strs, fmt.Sprintf("%d", s))
+		}
+		sqlStr += fmt.Sprintf(" and i_status in(%s)", strings.Join(strs, ","))
+	}
+	var count int64
+	err := mysql.db.QueryRow(sqlStr).Scan(&count)
+	return count, err
+}
+
+// RenameOrders 用户标识变更时迁移订单
+func (mysql *Mysql) RenameOrders(phone string, newphone string) error {
+	sqlStr := fmt.Sprintf("UPDATE t_order set s_phone='%s' where s_phone='%s'", Escape(newphone), Escape(phone))
+	return mysql.execSQL(sqlStr)
+}
+
+// InsertAudit 记录敏感操作
+func (mysql *Mysql) InsertAudit(audit *Audit) error {
+	sqlStr := fmt.Sprintf("INSERT INTO t_audit(s_phone, s_action, s_ip, s_detail, i_created) values('%s', '%s', '%s', '%s', %d)",
+		Escape(audit.Phone), Escape(audit.Action), Escape(audit.IP), Escape(strings.Replace(audit.Detail, ";", ",", -1)), audit.Time)
+	return mysql.execSQL(sqlStr)
+}
+
+// GetMonitorAddresses 获取监控地址及余额(不含 token)
+func (mysql *Mysql) GetMonitorAddresses() (map[string]*big.Int, error) {
+	sqlStr := "SELECT s_address, s_value FROM t_address where s_address not like '%-%'"
+	rows, err := mysql.db.Query(sqlStr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make(map[string]*big.Int)
+	for rows.Next() {
+		var address, value string
+		if err := rows.Scan(&address, &value); err != nil {
+			return nil, err
+		}
+		amount, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			amount = big.NewInt(0)
+		}
+		addresses[strings.ToLower(address)] = amount
+	}
+	return addresses, nil
+}
+
 func Escape(sql string) string {
 	dest := make([]byte, 0, 2*len(sql))
 	var escape byte
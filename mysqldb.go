@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 
 	"github.com/erick785/services/common"
@@ -21,6 +22,30 @@ func (mysql *Mysql) GetGasPrice() (*big.Int, error) {
 	return mysql.RPC.GetGasPrice()
 }
 
+// TokenDecimal 币种精度, token 为空时为原生币精度
+func (mysql *Mysql) TokenDecimal(token string) (int64, error) {
+	if len(token) == 0 {
+		return COINDECIMAL, nil
+	}
+	tokenInfo, err := mysql.InsertOrUpdateTokenInfo(strings.ToLower(token))
+	if err != nil {
+		return 0, err
+	}
+	return tokenInfo.Decimal, nil
+}
+
+// LookupTokenDecimal 已记录币种的精度, 只查询数据库; token 未被扫描记录时 ok 为 false
+func (mysql *Mysql) LookupTokenDecimal(token string) (int64, bool, error) {
+	if len(token) == 0 {
+		return COINDECIMAL, true, nil
+	}
+	tokenInfo, err := mysql.GetTokenInfo(Escape(strings.ToLower(token)))
+	if err != nil || tokenInfo == nil {
+		return 0, false, err
+	}
+	return tokenInfo.Decimal, true, nil
+}
+
 func (mysql *Mysql) GetHistory(address string, tokenAddress string, pagesize int64, pagenum int64) ([]*common.HistoryInfo, error) {
 	key := address
 	if len(tokenAddress) > 0 {
@@ -101,3 +126,39 @@ func (mysql *Mysql) GetHistory(address string, tokenAddress string, pagesize int
 	}
 	return htxs, nil
 }
+
+// GetHistories 汇总多个地址的历史交易, 按时间倒序分页, 地址间互转只保留一条
+func (mysql *Mysql) GetHistories(addresses []string, tokenAddress string, pagesize int64, pagenum int64) ([]*common.HistoryInfo, error) {
+	if len(addresses) == 1 {
+		return mysql.GetHistory(addresses[0], tokenAddress, pagesize, pagenum)
+	}
+	hashes := make(map[string]bool)
+	htxs := []*common.HistoryInfo{}
+	for _, address := range addresses {
+		ahtxs, err := mysql.GetHistory(address, tokenAddress, (pagenum+1)*pagesize, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, htx := range ahtxs {
+			if hashes[htx.Hash] {
+				continue
+			}
+			hashes[htx.Hash] = true
+			htxs = append(htxs, htx)
+		}
+	}
+	sort.SliceStable(htxs, func(i, j int) bool {
+		if htxs[i].Time != htxs[j].Time {
+			return htxs[i].Time > htxs[j].Time
+		}
+		return htxs[i].Height > htxs[j].Height
+	})
+	skip := pagenum * pagesize
+	if skip >= int64(len(htxs)) {
+		return []*common.HistoryInfo{}, nil
+	}
+	if skip+pagesize < int64(len(htxs)) {
+		return htxs[skip : skip+pagesize], nil
+	}
+	return htxs[skip:], nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erick785/services/common/log"
+)
+
+var (
+	// nonceGrace 已分配但未出现在内存池的序号保留时间, 超时视为丢失(gap)
+	nonceGrace = 60 * time.Second
+)
+
+// nonceReserved 离线签名预留序号的哈希标记
+const nonceReserved = "reserved"
+
+// NonceManager 交易序号分配器, 按地址串行分配并持久化
+type NonceManager struct {
+	db    *Mysql
+	rpc   *RPCClient
+	locks map[string]*sync.Mutex
+	sync.Mutex
+
+	ReserveTimeout time.Duration // 离线签名预留序号的保留时间
+}
+
+// NewNonceManager 创建交易序号分配器
+func NewNonceManager(db *Mysql, rpc *RPCClient) *NonceManager {
+	return &NonceManager{
+		db:             db,
+		rpc:            rpc,
+		locks:          make(map[string]*sync.Mutex),
+		ReserveTimeout: 24 * time.Hour,
+	}
+}
+
+func (nm *NonceManager) lock(address string) *sync.Mutex {
+	nm.Mutex.Lock()
+	defer nm.Mutex.Unlock()
+	l, ok := nm.locks[address]
+	if !ok {
+		l = &sync.Mutex{}
+		nm.locks[address] = l
+	}
+	return l
+}
+
+// Lock 锁定地址, 同一地址的分配与广播串行执行
+func (nm *NonceManager) Lock(address string) {
+	nm.lock(strings.ToLower(address)).Lock()
+}
+
+// Unlock 解锁地址
+func (nm *NonceManager) Unlock(address string) {
+	nm.lock(strings.ToLower(address)).Unlock()
+}
+
+// Allocate 分配下一个可用序号, 调用方需持有地址锁
+// 以节点序号为起点, 跳过内存池及宽限期内已分配的序号, 优先填补丢失的序号
+func (nm *NonceManager) Allocate(address string) (uint64, error) {
+	address = strings.ToLower(address)
+	nodeNonce, err := nm.rpc.getTransactionCount(address, nil)
+	if err != nil {
+		return 0, err
+	}
+	poolNonces, err := nm.rpc.GetPoolNonces(address)
+	if err != nil {
+		return 0, err
+	}
+	if err := nm.db.DeleteNoncesBelow(address, nodeNonce.Uint64()); err != nil {
+		return 0, err
+	}
+	allocated, err := nm.db.GetNonces(address)
+	if err != nil {
+		return 0, err
+	}
+
+	nonce := nodeNonce.Uint64()
+	for ; ; nonce++ {
+		if _, ok := poolNonces[nonce]; ok {
+			continue
+		}
+		nonceInfo, ok := allocated[nonce]
+		grace := nonceGrace
+		if ok && nonceInfo.Hash == nonceReserved {
+			grace = nm.ReserveTimeout
+		}
+		if ok && time.Now().Sub(time.Unix(nonceInfo.Time, 0)) < grace {
+			continue
+		}
+		if ok {
+			log.Warnf("[Nonce] %s fill nonce gap %d (%s)", address, nonce, nonceInfo.Hash)
+		}
+		break
+	}
+
+	if err := nm.db.UpdateNonce(&NonceInfo{
+		Address: address,
+		Nonce:   nonce,
+		Time:    time.Now().Unix(),
+	}); err != nil {
+		return 0, err
+	}
+	return nonce, nil
+}
+
+// Commit 记录序号对应的已广播交易
+func (nm *NonceManager) Commit(address string, nonce uint64, hash string) error {
+	return nm.db.UpdateNonce(&NonceInfo{
+		Address: strings.ToLower(address),
+		Nonce:   nonce,
+		Hash:    hash,
+		Time:    time.Now().Unix(),
+	})
+}
+
+// Release 释放未广播成功的序号
+func (nm *NonceManager) Release(address string, nonce uint64) error {
+	return nm.db.DeleteNonce(strings.ToLower(address), nonce)
+}
+
+// Reserve 为离线签名预留已分配的序号, 交易打包、Unreserve 或超过 ReserveTimeout 前不会再分配
+func (nm *NonceManager) Reserve(address string, nonce uint64) error {
+	return nm.Commit(address, nonce, nonceReserved)
+}
+
+// Unreserve 释放离线签名预留的序号, 调用方需持有地址锁
+func (nm *NonceManager) Unreserve(address string, nonce uint64) error {
+	address = strings.ToLower(address)
+	allocated, err := nm.db.GetNonces(address)
+	if err != nil {
+		return err
+	}
+	if nonceInfo, ok := allocated[nonce]; !ok || nonceInfo.Hash != nonceReserved {
+		return fmt.Errorf("nonce %d of %s not reserved", nonce, address)
+	}
+	return nm.Release(address, nonce)
+}
+
+// Reserved 序号是否仍为离线签名预留且未超过 ReserveTimeout
+func (nm *NonceManager) Reserved(address string, nonce uint64) (bool, error) {
+	allocated, err := nm.db.GetNonces(strings.ToLower(address))
+	if err != nil {
+		return false, err
+	}
+	nonceInfo, ok := allocated[nonce]
+	return ok && nonceInfo.Hash == nonceReserved && time.Now().Sub(time.Unix(nonceInfo.Time, 0)) < nm.ReserveTimeout, nil
+}
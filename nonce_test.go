@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	nonceReplaceRegex = regexp.MustCompile(`REPLACE INTO t_nonce.*values\('([^']*)', (\d+), '([^']*)', (\d+)\)`)
+	nonceDeleteRegex  = regexp.MustCompile(`DELETE FROM t_nonce where s_address='([^']*)' and i_nonce(=|<)(\d+)`)
+)
+
+const nonceAddress = "0x00000000000000000000000000000000000000aa"
+
+// fakeNonces 内存中的 t_nonce 表
+type fakeNonces map[uint64]*NonceInfo
+
+func (nonces fakeNonces) query(query string) ([]string, [][]driver.Value, error) {
+	if m := nonceReplaceRegex.FindStringSubmatch(query); m != nil {
+		nonce, _ := strconv.ParseUint(m[2], 10, 64)
+		created, _ := strconv.ParseInt(m[4], 10, 64)
+		nonces[nonce] = &NonceInfo{Address: m[1], Nonce: nonce, Hash: m[3], Time: created}
+		return nil, nil, nil
+	}
+	if m := nonceDeleteRegex.FindStringSubmatch(query); m != nil {
+		bound, _ := strconv.ParseUint(m[3], 10, 64)
+		for nonce := range nonces {
+			if (m[2] == "=" && nonce == bound) || (m[2] == "<" && nonce < bound) {
+				delete(nonces, nonce)
+			}
+		}
+		return nil, nil, nil
+	}
+	if strings.Contains(query, "FROM t_nonce") {
+		rows := [][]driver.Value{}
+		for _, nonceInfo := range nonces {
+			rows = append(rows, []driver.Value{nonceInfo.Address, int64(nonceInfo.Nonce), nonceInfo.Hash, nonceInfo.Time})
+		}
+		return []string{"s_address", "i_nonce", "s_hash", "i_created"}, rows, nil
+	}
+	return nil, nil, fmt.Errorf("unexpected query %s", query)
+}
+
+// newTestNonceManager 节点序号为 node, 内存池中有 pool 序号的分配器
+func newTestNonceManager(t *testing.T, nonces fakeNonces, node uint64, pool []uint64) (*NonceManager, func()) {
+	txs := []string{}
+	for _, nonce := range pool {
+		txs = append(txs, fmt.Sprintf(`"%d":{"from":"%s","nonce":"0x%x","hash":"0x%02x"}`, nonce, nonceAddress, nonce, nonce))
+	}
+	rpc := newFakeRPCMethods(map[string]string{
+		methodGetTransactionCount: fmt.Sprintf(`"0x%x"`, node),
+		methodTxPool:              fmt.Sprintf(`{"pending":{"%s":{%s}},"queued":{}}`, nonceAddress, strings.Join(txs, ",")),
+	})
+	db := newFakeMysql(t, nonces.query, rpc.URL)
+	return NewNonceManager(db, db.RPC), rpc.Close
+}
+
+func TestNonceAllocate(t *testing.T) {
+	ago := func(d time.Duration) int64 {
+		return time.Now().Add(-d).Unix()
+	}
+	for _, tc := range []struct {
+		name      string
+		node      uint64
+		pool      []uint64
+		allocated []*NonceInfo
+		want      uint64
+		remaining []uint64 // 分配后 t_nonce 中的序号
+	}{
+		{"node nonce", 5, nil, nil, 5, []uint64{5}},
+		{"skip pool", 5, []uint64{5, 6}, nil, 7, []uint64{7}},
+		{"skip allocated", 5, nil, []*NonceInfo{{Nonce: 5, Hash: "0x05", Time: ago(time.Second)}}, 6, []uint64{5, 6}},
+		{"fill gap", 5, []uint64{6}, []*NonceInfo{{Nonce: 5, Hash: "0x05", Time: ago(2 * nonceGrace)}}, 5, []uint64{5}},
+		{"skip reserved", 5, nil, []*NonceInfo{{Nonce: 5, Hash: nonceReserved, Time: ago(2 * nonceGrace)}}, 6, []uint64{5, 6}},
+		{"reserve expired", 5, nil, []*NonceInfo{{Nonce: 5, Hash: nonceReserved, Time: ago(25 * time.Hour)}}, 5, []uint64{5}},
+		{"drop mined", 5, nil, []*NonceInfo{{Nonce: 3, Hash: "0x03", Time: ago(time.Second)}, {Nonce: 4, Hash: nonceReserved, Time: ago(time.Second)}}, 5, []uint64{5}},
+	} {
+		nonces := fakeNonces{}
+		for _, nonceInfo := range tc.allocated {
+			nonceInfo.Address = nonceAddress
+			nonces[nonceInfo.Nonce] = nonceInfo
+		}
+		nm, done := newTestNonceManager(t, nonces, tc.node, tc.pool)
+		if nonce, err := nm.Allocate(nonceAddress); err != nil || nonce != tc.want {
+			t.Errorf("%s: allocate %d %v, expected %d", tc.name, nonce, err, tc.want)
+		}
+		if len(nonces) != len(tc.remaining) {
+			t.Errorf("%s: %d nonces remaining, expected %v", tc.name, len(nonces), tc.remaining)
+		}
+		for _, nonce := range tc.remaining {
+			if _, ok := nonces[nonce]; !ok {
+				t.Errorf("%s: nonce %d not allocated", tc.name, nonce)
+			}
+		}
+		done()
+	}
+}
+
+func TestNonceReleaseReserve(t *testing.T) {
+	nonces := fakeNonces{}
+	nm, done := newTestNonceManager(t, nonces, 5, nil)
+	defer done()
+
+	allocate := func(want uint64) {
+		if nonce, err := nm.Allocate(nonceAddress); err != nil || nonce != want {
+			t.Fatalf("allocate %d %v, expected %d", nonce, err, want)
+		}
+	}
+	reserved := func(nonce uint64, want bool) {
+		if ok, err := nm.Reserved(nonceAddress, nonce); err != nil || ok != want {
+			t.Fatalf("nonce %d reserved %v %v, expected %v", nonce, ok, err, want)
+		}
+	}
+
+	allocate(5)
+	allocate(6)
+	//释放的序号重新分配
+	if err := nm.Release(nonceAddress, 5); err != nil {
+		t.Fatal(err)
+	}
+	allocate(5)
+
+	//预留的序号不再分配, 取消预留后重新分配
+	if err := nm.Reserve(nonceAddress, 6); err != nil {
+		t.Fatal(err)
+	}
+	reserved(6, true)
+	reserved(5, false)
+	allocate(7)
+	if err := nm.Unreserve(nonceAddress, 5); err == nil {
+		t.Fatal("unreserved a nonce that is not reserved")
+	}
+	if err := nm.Unreserve(nonceAddress, 6); err != nil {
+		t.Fatal(err)
+	}
+	reserved(6, false)
+	allocate(6)
+
+	//超过 ReserveTimeout 的预留视为过期
+	if err := nm.Reserve(nonceAddress, 8); err != nil {
+		t.Fatal(err)
+	}
+	nm.ReserveTimeout = 0
+	reserved(8, false)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/erick785/services/common/log"
+	"github.com/erick785/services/common/signer"
+)
+
+// 订单状态
+const (
+	OrderPending   = iota // 已签名, 待广播
+	OrderSent             // 已广播, 内存池中
+	OrderFailed           // 广播失败
+	OrderMined            // 已打包
+	OrderConfirmed        // 已确认
+	OrderReverted         // 执行失败
+	OrderDropped          // 已丢弃(序号已被占用)
+	OrderCancelled        // 已取消(取消交易已确认)
+)
+
+// 订单类型, 由服务端设置
+const (
+	OrderTransfer = iota // 转账
+	OrderContract        // 合约调用/部署
+	OrderSchedule        // 定时转账
+	OrderSweep           // 归集, 不计入转出限额
+	OrderOffline         // buildtx 离线签名
+)
+
+// offlinePrefix 离线签名订单号前缀, 订单号为 buildtx-地址-序号
+const offlinePrefix = "buildtx-"
+
+// orderPrefixes 服务端生成的订单号前缀, 用户订单号不可使用
+var orderPrefixes = []string{sweepPrefix, schedulePrefix, offlinePrefix}
+
+// ReservedOrderID 订单号是否使用了服务端保留的前缀
+func ReservedOrderID(id string) bool {
+	for _, prefix := range orderPrefixes {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// OfflineOrderID 离线签名订单号
+func OfflineOrderID(from string, nonce uint64) string {
+	return fmt.Sprintf("%s%s-%d", offlinePrefix, strings.ToLower(from), nonce)
+}
+
+// CheckOrderID 检查用户提交的订单号, seen 记录同一批次中已出现的订单号;
+// 已有未失败的订单时返回该订单(重复提交返回已有结果), 待广播的订单返回错误, 失败的订单可重新提交
+func CheckOrderID(db *Mysql, phone string, id string, seen map[string]bool) (*OrderInfo, error) {
+	if len(id) == 0 {
+		return nil, errors.New("order id empty")
+	}
+	if ReservedOrderID(id) {
+		return nil, fmt.Errorf("order id %s reserved", id)
+	}
+	if seen[id] {
+		return nil, fmt.Errorf("order %s duplicated", id)
+	}
+	seen[id] = true
+	orderInfo, err := db.GetOrder(phone, id)
+	if err != nil {
+		return nil, err
+	}
+	if orderInfo != nil && orderInfo.Status == OrderPending {
+		return nil, fmt.Errorf("order %s is processing", id)
+	}
+	if orderInfo != nil && orderInfo.Status == OrderFailed {
+		return nil, nil
+	}
+	return orderInfo, nil
+}
+
+var orderStatus = []string{
+	"pending",
+	"sent",
+	"failed",
+	"mined",
+	"confirmed",
+	"reverted",
+	"dropped",
+	"cancelled",
+}
+
+// OrderTx 订单对应的交易参数, token 订单转为合约 transfer 调用
+func OrderTx(orderInfo *OrderInfo) (string, *big.Int, []byte, error) {
+	if err := ValidValue(orderInfo.Value); err != nil {
+		return "", nil, nil, err
+	}
+	if len(orderInfo.TokenAddress) > 0 {
+		data, err := TransferData(orderInfo.To, orderInfo.Value)
+		return orderInfo.TokenAddress, big.NewInt(0), data, err
+	}
+	if len(orderInfo.Data) > 0 {
+		data, err := hex.DecodeString(strings.TrimPrefix(orderInfo.Data, "0x"))
+		return orderInfo.To, orderInfo.Value, data, err
+	}
+	return orderInfo.To, orderInfo.Value, nil, nil
+}
+
+// tokenOrder 合约 transfer 调用按 token 转账记录, 计入 token 限额
+func tokenOrder(orderInfo *OrderInfo) error {
+	if len(orderInfo.TokenAddress) > 0 || len(orderInfo.To) == 0 || len(orderInfo.Data) == 0 {
+		return nil
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(orderInfo.Data, "0x"))
+	if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(data, transferMethodID) {
+		return nil
+	}
+	//只接受标准编码, 避免附加数据或金额绕过 token 限额
+	if len(data) != 4+32+32 || !bytes.Equal(data[4:16], make([]byte, 12)) || orderInfo.Value.Sign() != 0 {
+		return errors.New("token transfer must be a standard transfer call without value")
+	}
+	orderInfo.TokenAddress = strings.ToLower(orderInfo.To)
+	orderInfo.To = fmt.Sprintf("0x%x", data[16:36])
+	orderInfo.Value = new(big.Int).SetBytes(data[36:])
+	orderInfo.Data = ""
+	return nil
+}
+
+// OrderResult 订单处理结果
+type OrderResult struct {
+	ID    string `json:"id"`    // 订单号
+	Hash  string `json:"hash"`  // 交易哈希
+	Error string `json:"error"` // 错误信息
+}
+
+// SignOrder 检查转出限额, 分配序号并签名订单交易, 签名失败时释放序号; 调用方需持有地址锁
+func SignOrder(nonces *NonceManager, guard *OrderGuard, account *signer.Account, orderInfo *OrderInfo) error {
+	if err := tokenOrder(orderInfo); err != nil {
+		return err
+	}
+	if err := guard.Check(orderInfo); err != nil {
+		return err
+	}
+	nonce, err := nonces.Allocate(orderInfo.From)
+	if err != nil {
+		return err
+	}
+	to, value, data, err := OrderTx(orderInfo)
+	if err != nil {
+		releaseOrder(nonces, orderInfo.ID, orderInfo.From, nonce)
+		return err
+	}
+	signedhash, err := CreateTx(account, nonce, to, value, orderInfo.Gas, orderInfo.GasPrice, data)
+	if err != nil {
+		releaseOrder(nonces, orderInfo.ID, orderInfo.From, nonce)
+		return err
+	}
+	orderInfo.Nonce = nonce
+	orderInfo.Raw = fmt.Sprintf("0x%s", signedhash)
+	orderInfo.Fee = new(big.Int).Mul(orderInfo.GasPrice, new(big.Int).SetUint64(orderInfo.Gas))
+	orderInfo.Status = OrderPending
+	orderInfo.Error = ""
+	orderInfo.Time = time.Now().Unix()
+	orderInfo.Updated = orderInfo.Time
+	return nil
+}
+
+// BroadcastOrder 记录并广播已签名的订单交易, 订单在广播前已落库
+func BroadcastOrder(db *Mysql, nonces *NonceManager, orderInfo *OrderInfo) error {
+	if err := db.UpdateOrder(orderInfo); err != nil {
+		releaseOrder(nonces, orderInfo.ID, orderInfo.From, orderInfo.Nonce)
+		return err
+	}
+
+	hash, err := db.RPC.SendRawTransaction(orderInfo.Raw)
+	if err != nil {
+		releaseOrder(nonces, orderInfo.ID, orderInfo.From, orderInfo.Nonce)
+		orderInfo.Status = OrderFailed
+		orderInfo.Error = err.Error()
+		if err := db.UpdateOrder(orderInfo); err != nil {
+			log.Errorf("[Order] %s UpdateOrder err %v", orderInfo.ID, err)
+		}
+		return err
+	}
+
+	if err := nonces.Commit(orderInfo.From, orderInfo.Nonce, hash); err != nil {
+		log.Errorf("[Order] %s Commit nonce %d err %v", orderInfo.ID, orderInfo.Nonce, err)
+	}
+	orderInfo.Hash = hash
+	orderInfo.Status = OrderSent
+	if err := db.UpdateOrder(orderInfo); err != nil {
+		log.Errorf("[Order] %s UpdateOrder err %v", orderInfo.ID, err)
+	}
+	return nil
+}
+
+// SendOrder 签名并广播订单交易
+func SendOrder(db *Mysql, nonces *NonceManager, guard *OrderGuard, account *signer.Account, orderInfo *OrderInfo) error {
+	if err := SignOrder(nonces, guard, account, orderInfo); err != nil {
+		return err
+	}
+	return BroadcastOrder(db, nonces, orderInfo)
+}
+
+// SendOrdersAtomic 全部订单签名成功后才广播, 任一签名失败则释放已分配的序号且不广播;
+// 广播中途失败时, 其后的订单不再广播
+func SendOrdersAtomic(db *Mysql, nonces *NonceManager, guard *OrderGuard, account *signer.Account, orderInfos []*OrderInfo) ([]*OrderResult, bool) {
+	results := []*OrderResult{}
+	for _, orderInfo := range orderInfos {
+		results = append(results, &OrderResult{ID: orderInfo.ID})
+	}
+
+	for i, orderInfo := range orderInfos {
+		if err := SignOrder(nonces, guard, account, orderInfo); err != nil {
+			results[i].Error = err.Error()
+			for _, signed := range orderInfos[:i] {
+				releaseOrder(nonces, signed.ID, signed.From, signed.Nonce)
+			}
+			return results, false
+		}
+	}
+
+	for i, orderInfo := range orderInfos {
+		if err := BroadcastOrder(db, nonces, orderInfo); err != nil {
+			results[i].Error = err.Error()
+			for j, unsent := range orderInfos[i+1:] {
+				releaseOrder(nonces, unsent.ID, unsent.From, unsent.Nonce)
+				results[i+1+j].Error = "batch aborted"
+			}
+			return results, false
+		}
+		results[i].Hash = orderInfo.Hash
+	}
+	return results, true
+}
+
+// BuildOrder 检查转出限额, 分配并预留序号, 创建未签名交易; 订单记录后由 signtx 签名, 调用方需持有地址锁
+func BuildOrder(db *Mysql, nonces *NonceManager, guard *OrderGuard, orderInfo *OrderInfo) error {
+	if err := tokenOrder(orderInfo); err != nil {
+		return err
+	}
+	if err := guard.Check(orderInfo); err != nil {
+		return err
+	}
+	nonce, err := nonces.Allocate(orderInfo.From)
+	if err != nil {
+		return err
+	}
+	orderInfo.ID = OfflineOrderID(orderInfo.From, nonce)
+	to, value, data, err := OrderTx(orderInfo)
+	if err != nil {
+		releaseOrder(nonces, orderInfo.ID, orderInfo.From, nonce)
+		return err
+	}
+	raw, err := BuildTx(nonce, to, value, orderInfo.Gas, orderInfo.GasPrice, data)
+	if err != nil {
+		releaseOrder(nonces, orderInfo.ID, orderInfo.From, nonce)
+		return err
+	}
+	if err := nonces.Reserve(orderInfo.From, nonce); err != nil {
+		releaseOrder(nonces, orderInfo.ID, orderInfo.From, nonce)
+		return err
+	}
+	orderInfo.Type = OrderOffline
+	orderInfo.Nonce = nonce
+	orderInfo.Raw = fmt.Sprintf("0x%s", raw)
+	orderInfo.Fee = new(big.Int).Mul(orderInfo.GasPrice, new(big.Int).SetUint64(orderInfo.Gas))
+	orderInfo.Status = OrderPending
+	orderInfo.Error = ""
+	orderInfo.Time = time.Now().Unix()
+	orderInfo.Updated = orderInfo.Time
+	//订单计入限额, 保存失败则不返回交易
+	if err := db.UpdateOrder(orderInfo); err != nil {
+		releaseOrder(nonces, orderInfo.ID, orderInfo.From, nonce)
+		return err
+	}
+	return nil
+}
+
+// replaceOrder 记录以相同序号替换的交易, 由调用方保存订单
+func replaceOrder(orderInfo *OrderInfo, hash string, raw string, gas uint64, gasPrice *big.Int) {
+	orderInfo.Replaced = append(orderInfo.Replaced, orderInfo.Hash)
+	orderInfo.Hash = hash
+	orderInfo.Raw = raw
+	orderInfo.Gas = gas
+	orderInfo.GasPrice = gasPrice
+	orderInfo.Fee = new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gas))
+	orderInfo.Updated = time.Now().Unix()
+}
+
+func releaseOrder(nonces *NonceManager, id string, from string, nonce uint64) {
+	if err := nonces.Release(from, nonce); err != nil {
+		log.Errorf("[Order] %s Release nonce %d err %v", id, nonce, err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+var orderIDRegex = regexp.MustCompile(`s_phone='([^']*)' and s_id='([^']*)'`)
+
+func TestCheckOrderID(t *testing.T) {
+	//已有订单: 用户/订单号 -> 状态
+	orders := map[string]int{
+		"user/pending":   OrderPending,
+		"user/sent":      OrderSent,
+		"user/failed":    OrderFailed,
+		"user/confirmed": OrderConfirmed,
+		"other/new":      OrderSent,
+	}
+	db := newFakeMysql(t, func(query string) ([]string, [][]driver.Value, error) {
+		m := orderIDRegex.FindStringSubmatch(query)
+		if m == nil {
+			return nil, nil, errors.New("unexpected query " + query)
+		}
+		if m[2] == "dberr" {
+			return nil, nil, errors.New("db error")
+		}
+		status, ok := orders[m[1]+"/"+m[2]]
+		if !ok {
+			return []string{}, nil, nil
+		}
+		return []string{"s_id", "s_phone", "i_type", "s_from", "s_token", "s_to", "s_value", "s_data", "i_gas", "s_gasprice", "s_fee", "i_nonce", "s_raw", "s_hash", "s_replaced", "s_cancel", "i_height", "i_status", "s_error", "i_created", "i_updated"},
+			[][]driver.Value{{m[2], m[1], int64(OrderTransfer), "", "", "", "0", "", int64(0), "0", "0", int64(0), "", "0x" + m[2], "[]", "", int64(0), int64(status), "", int64(0), int64(0)}}, nil
+	}, "")
+
+	for _, tc := range []struct {
+		name     string
+		ids      []string
+		existing string // 最后一个订单号返回的已有订单哈希
+		err      string // 最后一个订单号的错误
+	}{
+		{"new", []string{"new"}, "", ""},
+		{"empty", []string{""}, "", "order id empty"},
+		{"reserved", []string{offlinePrefix + "0x01-1"}, "", "order id " + offlinePrefix + "0x01-1 reserved"},
+		{"batch duplicate", []string{"new", "new"}, "", "order new duplicated"},
+		{"processing", []string{"pending"}, "", "order pending is processing"},
+		{"sent", []string{"sent"}, "0xsent", ""},
+		{"confirmed", []string{"confirmed"}, "0xconfirmed", ""},
+		{"failed resubmit", []string{"failed"}, "", ""},
+		{"db error", []string{"dberr"}, "", "db error"},
+	} {
+		seen := map[string]bool{}
+		var orderInfo *OrderInfo
+		var err error
+		for _, id := range tc.ids {
+			orderInfo, err = CheckOrderID(db, "user", id, seen)
+		}
+		msg, hash := "", ""
+		if err != nil {
+			msg = err.Error()
+		}
+		if msg != tc.err {
+			t.Errorf("%s: error %q, expected %q", tc.name, msg, tc.err)
+		}
+		if orderInfo != nil {
+			hash = orderInfo.Hash
+		}
+		if hash != tc.existing {
+			t.Errorf("%s: existing order %q, expected %q", tc.name, hash, tc.existing)
+		}
+	}
+}
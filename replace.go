@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/erick785/services/common/log"
+	"github.com/erick785/services/common/signer"
+)
+
+// ReplaceResult 替换交易结果
+type ReplaceResult struct {
+	Hash     string     `json:"hash"`            // 新交易哈希
+	Replaced string     `json:"replaced"`        // 被替换的交易哈希
+	Nonce    uint64     `json:"nonce"`           // 交易序号
+	Gas      uint64     `json:"gas"`             // 燃料大小
+	GasPrice *big.Int   `json:"gas_price"`       // 燃料单价
+	Order    *OrderInfo `json:"order,omitempty"` // 对应的订单
+}
+
+// ReplaceGasPrice 替换交易的燃料单价, 至少为原单价提高 percent;
+// 未指定时取快档价格与最低单价中的较大者
+func ReplaceGasPrice(oracle *GasOracle, old *big.Int, percent int64, gasPrice *big.Int) (*big.Int, error) {
+	min := new(big.Int).Div(new(big.Int).Mul(old, big.NewInt(100+percent)), big.NewInt(100))
+	if min.Cmp(old) <= 0 {
+		min = new(big.Int).Add(old, big.NewInt(1))
+	}
+	if gasPrice != nil && gasPrice.Sign() > 0 {
+		if gasPrice.Cmp(min) < 0 {
+			return nil, fmt.Errorf("gas price %v lower than %v", gasPrice, min)
+		}
+		return gasPrice, nil
+	}
+	fast, err := oracle.GasPrice(SpeedFast)
+	if err != nil {
+		return nil, err
+	}
+	if fast.Cmp(min) > 0 {
+		return fast, nil
+	}
+	return min, nil
+}
+
+// ReplaceTx 以相同序号、更高燃料单价替换未打包的交易: 加速时保持原交易内容,
+// 取消时改为转给自己的零金额交易; 交易属于订单时同时更新订单
+func ReplaceTx(db *Mysql, nonces *NonceManager, account *signer.Account, payload *TxPayload, gasPrice *big.Int, cancel bool) (*ReplaceResult, error) {
+	to, value, gas, data := payload.To, payload.Value, payload.Gas, payload.Data
+	if cancel {
+		to, value, gas, data = payload.From, big.NewInt(0), 21000, nil
+	}
+	signedhash, err := CreateTx(account, payload.Nonce, to, value, gas, gasPrice, data)
+	if err != nil {
+		return nil, err
+	}
+	raw := fmt.Sprintf("0x%s", signedhash)
+	hash, err := db.RPC.SendRawTransaction(raw)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("[Replace] %s nonce %d gas price %s -> %s, hash %s -> %s, cancel %v", payload.From, payload.Nonce, payload.GasPrice, gasPrice, payload.Hash, hash, cancel)
+
+	if err := nonces.Commit(payload.From, payload.Nonce, hash); err != nil {
+		log.Errorf("[Replace] %s Commit nonce %d err %v", payload.From, payload.Nonce, err)
+	}
+	result := &ReplaceResult{
+		Hash:     hash,
+		Replaced: payload.Hash,
+		Nonce:    payload.Nonce,
+		Gas:      gas,
+		GasPrice: gasPrice,
+	}
+
+	//交易已广播, 订单更新失败只记录日志
+	orderInfo, err := db.GetOrderByHash(payload.Hash)
+	if err != nil {
+		log.Errorf("[Replace] %s GetOrderByHash err %v", payload.Hash, err)
+		return result, nil
+	}
+	if orderInfo == nil {
+		return result, nil
+	}
+	replaceOrder(orderInfo, hash, raw, gas, gasPrice)
+	if cancel {
+		orderInfo.Cancel = hash
+	}
+	if err := db.UpdateOrder(orderInfo); err != nil {
+		log.Errorf("[Replace] order %s UpdateOrder err %v", orderInfo.ID, err)
+	}
+	result.Order = orderInfo
+	return result, nil
+}
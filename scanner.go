@@ -11,7 +11,7 @@ import (
 )
 
 // Scanning sync new blocks and new pending txs from main blockchain.
-func Scanning(ctx context.Context, db *Mysql, rpc *RPCClient, startHeight *big.Int) {
+func Scanning(ctx context.Context, db *Mysql, rpc *RPCClient, oracle *GasOracle, startHeight *big.Int) {
 	//最新区块
 	var curBlock *Block
 	//初始化 回滚
@@ -84,6 +84,7 @@ func Scanning(ctx context.Context, db *Mysql, rpc *RPCClient, startHeight *big.I
 				log.Errorf("[Scanning] InsertPendingTxs --- %s", err)
 				continue
 			}
+			oracle.SetPending(txs)
 			pendingTxs = map[string]*Transaction{}
 			for _, tx := range txs {
 				pendingTxs[tx.TxHash()] = tx
@@ -112,6 +113,7 @@ func Scanning(ctx context.Context, db *Mysql, rpc *RPCClient, startHeight *big.I
 				log.Errorf("[Scanning] InsertBlock %s --- %s", curBlock.Number(), err)
 				continue
 			}
+			oracle.AddBlock(curBlock)
 		}
 		if curBlock != nil {
 			fromNumber = new(big.Int).Add(curBlock.Number(), big.NewInt(1))
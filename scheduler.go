@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erick785/services/common/cron"
+	"github.com/erick785/services/common/log"
+	"github.com/erick785/services/common/signer"
+	"github.com/erick785/services/common/wallet"
+)
+
+// 计划状态
+const (
+	ScheduleActive    = iota // 等待执行
+	ScheduleCancelled        // 已取消
+	ScheduleFinished         // 已完成
+)
+
+// 余额不足处理策略
+const (
+	PolicyRetry = "retry" // 稍后重试, 超过重试次数后跳过本次
+	PolicySkip  = "skip"  // 跳过本次
+)
+
+// schedulePrefix 计划执行订单号前缀, 订单号为 schedule-计划号-执行次数
+const schedulePrefix = "schedule-"
+
+var (
+	// scheduleInterval 计划检查间隔
+	scheduleInterval = 10 * time.Second
+	// retryInterval 执行失败后重试间隔
+	retryInterval = 5 * time.Minute
+)
+
+// 执行结果
+const (
+	execOk     = iota // 已广播
+	execFunds         // 余额不足
+	execRule          // 违反转出规则
+	execFailed        // 其它错误
+)
+
+// Scheduler 定时转账: 到期后按 /send 相同的规则检查、签名并广播
+type Scheduler struct {
+	db     *Mysql
+	wltdb  *wallet.Mysql
+	signer signer.Signer
+	nonces *NonceManager
+	limits *LimitChecker
+	oracle *GasOracle
+
+	sync.Mutex
+}
+
+// NewScheduler 创建定时转账任务
+func NewScheduler(db *Mysql, wltdb *wallet.Mysql, wltsigner signer.Signer, nonces *NonceManager, limits *LimitChecker, oracle *GasOracle) *Scheduler {
+	return &Scheduler{
+		db:     db,
+		wltdb:  wltdb,
+		signer: wltsigner,
+		nonces: nonces,
+		limits: limits,
+		oracle: oracle,
+	}
+}
+
+// ScheduleOrderPrefix 计划执行订单号前缀
+func ScheduleOrderPrefix(id string) string {
+	return fmt.Sprintf("%s%s-", schedulePrefix, id)
+}
+
+// ScheduleOrderID 计划第 run 次执行的订单号
+func ScheduleOrderID(id string, run int64) string {
+	return fmt.Sprintf("%s%d", ScheduleOrderPrefix(id), run)
+}
+
+// NextRun 计算下次执行时间, 没有下次执行时返回 0
+func NextRun(spec string, t time.Time) (int64, error) {
+	if len(spec) == 0 {
+		return 0, nil
+	}
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return 0, err
+	}
+	next := schedule.Next(t)
+	if next.IsZero() {
+		return 0, nil
+	}
+	return next.Unix(), nil
+}
+
+// Scheduling 定时执行到期的计划
+func (scheduler *Scheduler) Scheduling(ctx context.Context) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		scheduler.Lock()
+		scheduleInfos, err := scheduler.db.GetDueSchedules(time.Now().Unix())
+		if err != nil {
+			log.Errorf("[Scheduling] GetDueSchedules --- %s", err)
+		}
+		for _, scheduleInfo := range scheduleInfos {
+			if err := scheduler.run(scheduleInfo); err != nil {
+				log.Errorf("[Scheduling] schedule %s --- %s", scheduleInfo.ID, err)
+			}
+		}
+		scheduler.Unlock()
+	}
+}
+
+// Cancel 取消用户的计划
+func (scheduler *Scheduler) Cancel(phone string, id string) (*ScheduleInfo, error) {
+	scheduler.Lock()
+	defer scheduler.Unlock()
+	scheduleInfo, err := scheduler.db.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	if scheduleInfo == nil || scheduleInfo.Phone != phone {
+		return nil, fmt.Errorf("schedule %s not found", id)
+	}
+	if scheduleInfo.Status != ScheduleActive {
+		return scheduleInfo, nil
+	}
+	scheduleInfo.Status = ScheduleCancelled
+	scheduleInfo.Updated = time.Now().Unix()
+	return scheduleInfo, scheduler.db.UpdateSchedule(scheduleInfo)
+}
+
+// run 执行一次计划并计算下次执行时间
+func (scheduler *Scheduler) run(scheduleInfo *ScheduleInfo) error {
+	orderID := ScheduleOrderID(scheduleInfo.ID, scheduleInfo.Runs+1)
+	orderInfo, err := scheduler.db.GetOrder(scheduleInfo.Phone, orderID)
+	if err != nil {
+		return err
+	}
+	result := execOk
+	//已广播的订单不再重复执行
+	if orderInfo == nil || orderInfo.Status == OrderFailed {
+		orderInfo, result = scheduler.execute(scheduleInfo, orderID)
+	}
+
+	now := time.Now()
+	scheduleInfo.LastOrder = orderInfo.ID
+	scheduleInfo.LastHash = orderInfo.Hash
+	scheduleInfo.LastError = orderInfo.Error
+	scheduleInfo.Updated = now.Unix()
+	retry := result == execFailed || (result == execFunds && scheduleInfo.Policy == PolicyRetry)
+	if result != execOk && retry && scheduleInfo.Retries < scheduleInfo.MaxRetries {
+		scheduleInfo.Retries++
+		scheduleInfo.Next = now.Add(retryInterval).Unix()
+		log.Warnf("[Scheduling] schedule %s retry %d --- %s", scheduleInfo.ID, scheduleInfo.Retries, orderInfo.Error)
+		return scheduler.db.UpdateSchedule(scheduleInfo)
+	}
+	if result != execOk {
+		log.Warnf("[Scheduling] schedule %s skip run %d --- %s", scheduleInfo.ID, scheduleInfo.Runs+1, orderInfo.Error)
+	} else {
+		log.Infof("[Scheduling] schedule %s run %d, hash %s", scheduleInfo.ID, scheduleInfo.Runs+1, orderInfo.Hash)
+	}
+
+	scheduleInfo.Runs++
+	scheduleInfo.Retries = 0
+	next, err := NextRun(scheduleInfo.Cron, now)
+	if err != nil {
+		return err
+	}
+	if next == 0 || (scheduleInfo.Count > 0 && scheduleInfo.Runs >= scheduleInfo.Count) {
+		scheduleInfo.Status = ScheduleFinished
+	} else {
+		scheduleInfo.Next = next
+	}
+	return scheduler.db.UpdateSchedule(scheduleInfo)
+}
+
+// execute 检查余额及转出规则后签名广播, 未广播的执行也记录为失败订单
+func (scheduler *Scheduler) execute(scheduleInfo *ScheduleInfo, orderID string) (*OrderInfo, int) {
+	orderInfo := &OrderInfo{
+		ID:           orderID,
+		Phone:        scheduleInfo.Phone,
+		Type:         OrderSchedule,
+		TokenAddress: scheduleInfo.TokenAddress,
+		To:           scheduleInfo.To,
+		Value:        new(big.Int).Set(scheduleInfo.Value),
+		Gas:          scheduleInfo.Gas,
+		GasPrice:     new(big.Int).Set(scheduleInfo.GasPrice),
+		Fee:          big.NewInt(0),
+		Time:         time.Now().Unix(),
+	}
+	orderInfo.Updated = orderInfo.Time
+	fail := func(result int, err error) (*OrderInfo, int) {
+		orderInfo.Status = OrderFailed
+		orderInfo.Error = err.Error()
+		if err := scheduler.db.UpdateOrder(orderInfo); err != nil {
+			log.Errorf("[Scheduling] %s UpdateOrder err %v", orderInfo.ID, err)
+		}
+		return orderInfo, result
+	}
+
+	wlt, err := scheduler.wltdb.GetWallet(scheduleInfo.Phone)
+	if err != nil {
+		return fail(execFailed, err)
+	}
+	if wlt == nil {
+		return fail(execRule, fmt.Errorf("wallet %s not found", scheduleInfo.Phone))
+	}
+	//设置了口令的钱包需要用户提供口令, 观察钱包没有私钥, 无法定时转账
+	account, err := DefaultAccount(scheduler.signer, wlt, "")
+	if err == wallet.ErrPassphrase || err == wallet.ErrWatchOnly {
+		return fail(execRule, err)
+	}
+	if err != nil {
+		return fail(execFailed, err)
+	}
+	orderInfo.From = strings.ToLower(ToAddress(account.PublicKey))
+
+	scheduler.nonces.Lock(orderInfo.From)
+	defer scheduler.nonces.Unlock(orderInfo.From)
+	scheduler.limits.Lock(wlt.Name)
+	defer scheduler.limits.Unlock(wlt.Name)
+
+	if orderInfo.Gas == 0 {
+		orderInfo.Gas = 21000
+		if len(orderInfo.TokenAddress) > 0 {
+			orderInfo.Gas = uint64(TransferGas)
+		}
+	}
+	if orderInfo.GasPrice.Sign() == 0 {
+		gasprice, err := scheduler.oracle.GasPrice(SpeedStandard)
+		if err != nil {
+			return fail(execFailed, err)
+		}
+		orderInfo.GasPrice = gasprice
+	}
+
+	fee := new(big.Int).Mul(orderInfo.GasPrice, new(big.Int).SetUint64(orderInfo.Gas))
+	cost := new(big.Int).Add(orderInfo.Value, fee)
+	if len(orderInfo.TokenAddress) > 0 {
+		cost = fee
+	}
+	if amount, _, err := scheduler.db.RPC.GetBalanceAndNone(orderInfo.From, ""); err != nil {
+		return fail(execFailed, err)
+	} else if amount.Cmp(cost) < 0 {
+		return fail(execFunds, fmt.Errorf("not sufficient funds %v < %v", amount, cost))
+	}
+	if len(orderInfo.TokenAddress) > 0 {
+		if tokenAmount, err := scheduler.db.RPC.getBalance(orderInfo.From, orderInfo.TokenAddress, nil); err != nil {
+			return fail(execFailed, err)
+		} else if tokenAmount.Cmp(orderInfo.Value) < 0 {
+			return fail(execFunds, fmt.Errorf("not sufficient token funds %v < %v", tokenAmount, orderInfo.Value))
+		}
+	}
+
+	rules := scheduler.limits.Rules(wlt)
+	if err := scheduler.limits.CheckAccount(rules, wlt, 1); err != nil {
+		return fail(execRule, err)
+	}
+
+	if err := SendOrder(scheduler.db, scheduler.nonces, scheduler.limits.Guard(wlt, rules), account, orderInfo); err != nil {
+		if ruleErrCode(err, codeOk) != codeOk {
+			return fail(execRule, err)
+		}
+		if orderInfo.Status != OrderFailed {
+			return fail(execFailed, err)
+		}
+		return orderInfo, execFailed
+	}
+	return orderInfo, execOk
+}
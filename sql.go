@@ -43,4 +43,78 @@ CREATE TABLE IF NOT EXISTS t_history (
   INDEX (s_address),
   UNIQUE (s_address, s_hash)
 );
+
+CREATE TABLE IF NOT EXISTS t_nonce (
+  id int(11) NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  s_address char(100) NOT NULL comment '账户地址',
+  i_nonce bigint(20) NOT NULL comment '交易序号',
+  s_hash char(100) NOT NULL comment '交易哈希',
+  i_created int(11) NOT NULL comment '分配时间',
+  UNIQUE (s_address, i_nonce)
+);
+
+CREATE TABLE IF NOT EXISTS t_order (
+  id int(11) NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  s_id char(100) NOT NULL comment '订单号',
+  s_phone char(100) NOT NULL comment '用户标识',
+  i_type int(11) NOT NULL comment '订单类型',
+  s_from char(100) NOT NULL comment '发送方',
+  s_token char(100) NOT NULL comment 'token合约地址',
+  s_to char(100) NOT NULL comment '接收方',
+  s_value char(100) NOT NULL comment '接收金额',
+  s_data longtext NOT NULL comment '调用数据',
+  i_gas bigint(20) NOT NULL comment '燃料大小',
+  s_gasprice char(100) NOT NULL comment '燃料单价',
+  s_fee char(100) NOT NULL comment '手续费',
+  i_nonce bigint(20) NOT NULL comment '交易序号',
+  s_raw longtext NOT NULL comment '签名交易',
+  s_hash char(100) NOT NULL comment '交易哈希',
+  s_replaced longtext NOT NULL comment '被替换的交易哈希',
+  s_cancel char(100) NOT NULL comment '取消交易哈希',
+  i_height int(11) NOT NULL comment '交易所在区块高度',
+  i_status int(11) NOT NULL comment '订单状态',
+  s_error longtext NOT NULL comment '错误信息',
+  i_created int(11) NOT NULL comment '创建时间',
+  i_updated int(11) NOT NULL comment '最近广播时间',
+  UNIQUE INDEX (s_phone, s_id),
+  INDEX (s_id),
+  INDEX (s_from, s_token, i_created),
+  INDEX (s_hash),
+  INDEX (i_status)
+);
+CREATE TABLE IF NOT EXISTS t_schedule (
+  id int(11) NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  s_id char(100) NOT NULL comment '计划号',
+  s_phone char(100) NOT NULL comment '用户标识',
+  s_token char(100) NOT NULL comment 'token合约地址',
+  s_to char(100) NOT NULL comment '接收方',
+  s_value char(100) NOT NULL comment '接收金额',
+  i_gas bigint(20) NOT NULL comment '燃料大小',
+  s_gasprice char(100) NOT NULL comment '燃料单价',
+  s_cron char(100) NOT NULL comment '执行周期',
+  i_next bigint(20) NOT NULL comment '下次执行时间',
+  i_count bigint(20) NOT NULL comment '最多执行次数',
+  i_runs bigint(20) NOT NULL comment '已执行次数',
+  s_policy char(100) NOT NULL comment '余额不足处理策略',
+  i_retries bigint(20) NOT NULL comment '本次已重试次数',
+  i_max_retries bigint(20) NOT NULL comment '最多重试次数',
+  i_status int(11) NOT NULL comment '计划状态',
+  s_last_order char(100) NOT NULL comment '最近执行的订单号',
+  s_last_hash char(100) NOT NULL comment '最近执行的交易哈希',
+  s_last_error longtext NOT NULL comment '最近执行的错误信息',
+  i_created int(11) NOT NULL comment '创建时间',
+  i_updated int(11) NOT NULL comment '更新时间',
+  UNIQUE INDEX (s_id),
+  INDEX (s_phone),
+  INDEX (i_status, i_next)
+);
+CREATE TABLE IF NOT EXISTS t_audit (
+  id int(11) NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  s_phone char(100) NOT NULL comment '用户标识',
+  s_action char(100) NOT NULL comment '操作',
+  s_ip char(100) NOT NULL comment '请求来源',
+  s_detail longtext NOT NULL comment '详情',
+  i_created int(11) NOT NULL comment '操作时间',
+  INDEX (s_phone)
+);
 `
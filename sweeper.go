@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erick785/services/common/log"
+	"github.com/erick785/services/common/signer"
+	"github.com/erick785/services/common/wallet"
+)
+
+// sweepPrefix 归集订单号前缀
+const sweepPrefix = "sweep-"
+
+// sweepGas 归集转账燃料大小
+var sweepGas uint64 = 21000
+
+// Sweeper 将用户地址余额归集到热钱包地址
+type Sweeper struct {
+	db     *Mysql
+	wltdb  *wallet.Mysql
+	signer signer.Signer
+	nonces *NonceManager
+
+	Address   string        // 归集地址
+	Threshold *big.Int      // 余额超过阈值才归集
+	Interval  time.Duration // 定时归集间隔, 0 只手动归集
+
+	sync.Mutex
+}
+
+// NewSweeper 创建归集任务
+func NewSweeper(db *Mysql, wltdb *wallet.Mysql, wltsigner signer.Signer, nonces *NonceManager, address string, threshold *big.Int, interval time.Duration) *Sweeper {
+	return &Sweeper{
+		db:        db,
+		wltdb:     wltdb,
+		signer:    wltsigner,
+		nonces:    nonces,
+		Address:   strings.ToLower(address),
+		Threshold: threshold,
+		Interval:  interval,
+	}
+}
+
+// Sweeping 定时归集
+func (sweeper *Sweeper) Sweeping(ctx context.Context) {
+	if sweeper.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(sweeper.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := sweeper.Sweep(); err != nil {
+			log.Errorf("[Sweeping] Sweep --- %s", err)
+		}
+	}
+}
+
+// Sweep 归集一次, 返回本次创建的归集订单
+// 跳过归集地址自身、余额不足阈值及有未完成转出交易的地址
+func (sweeper *Sweeper) Sweep() ([]*OrderInfo, error) {
+	sweeper.Lock()
+	defer sweeper.Unlock()
+
+	if !ValidAddress(sweeper.Address) {
+		return nil, fmt.Errorf("invalid sweep address %s", sweeper.Address)
+	}
+	addresses, err := sweeper.db.GetMonitorAddresses()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := sweeper.pendingAddresses()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := sweeper.walletAccounts()
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := sweeper.db.GetGasPrice()
+	if err != nil {
+		return nil, err
+	}
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(sweepGas))
+
+	orderInfos := []*OrderInfo{}
+	for address, amount := range addresses {
+		if address == sweeper.Address || amount.Cmp(sweeper.Threshold) < 0 || pending[address] {
+			continue
+		}
+		account, ok := accounts[address]
+		if !ok {
+			continue
+		}
+		orderInfo, err := sweeper.sweep(account, address, gasPrice, fee)
+		if err != nil {
+			log.Errorf("[Sweeping] %s --- %s", address, err)
+			continue
+		}
+		if orderInfo != nil {
+			orderInfos = append(orderInfos, orderInfo)
+		}
+	}
+	return orderInfos, nil
+}
+
+func (sweeper *Sweeper) sweep(account *signer.Account, address string, gasPrice *big.Int, fee *big.Int) (*OrderInfo, error) {
+	sweeper.nonces.Lock(address)
+	defer sweeper.nonces.Unlock(address)
+
+	//扫描数据可能滞后, 直接查询节点最新余额
+	amount, err := sweeper.db.RPC.getBalance(address, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Cmp(sweeper.Threshold) < 0 || amount.Cmp(fee) <= 0 {
+		return nil, nil
+	}
+	if poolNonces, err := sweeper.db.RPC.GetPoolNonces(address); err != nil {
+		return nil, err
+	} else if len(poolNonces) > 0 {
+		return nil, nil
+	}
+
+	orderInfo := &OrderInfo{
+		ID:       fmt.Sprintf("%s%s-%d", sweepPrefix, address, time.Now().UnixNano()),
+		Phone:    account.Wallet,
+		Type:     OrderSweep,
+		From:     address,
+		To:       sweeper.Address,
+		Value:    new(big.Int).Sub(amount, fee),
+		Gas:      sweepGas,
+		GasPrice: gasPrice,
+	}
+	if err := SendOrder(sweeper.db, sweeper.nonces, nil, account, orderInfo); err != nil {
+		return nil, err
+	}
+	log.Infof("[Sweeping] %s -> %s value %s, hash %s", address, sweeper.Address, orderInfo.Value, orderInfo.Hash)
+	return orderInfo, nil
+}
+
+// pendingAddresses 有未完成订单的地址
+func (sweeper *Sweeper) pendingAddresses() (map[string]bool, error) {
+	orderInfos, err := sweeper.db.GetOrdersByStatus(OrderPending, OrderSent)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[string]bool)
+	for _, orderInfo := range orderInfos {
+		pending[strings.ToLower(orderInfo.From)] = true
+	}
+	return pending, nil
+}
+
+// walletAccounts 全部用户地址对应的签名账户
+func (sweeper *Sweeper) walletAccounts() (map[string]*signer.Account, error) {
+	wlts, err := sweeper.wltdb.GetWallets()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make(map[string]*signer.Account)
+	for _, wlt := range wlts {
+		//观察钱包没有私钥, 不归集
+		if wlt.WatchOnly() {
+			continue
+		}
+		for _, index := range wlt.AddressIndexes() {
+			//口令地址没有口令无法签名, 不归集
+			if len(index.Passphrase) > 0 {
+				continue
+			}
+			account, err := signer.NewAccount(sweeper.signer, wlt.Name, AddressDerivationPath(COINTYPE, index.Account, index.Index).String(), "")
+			if err != nil {
+				log.Errorf("[Sweeping] PublicKey(%s) --- %s", wlt.Name, err)
+				continue
+			}
+			accounts[strings.ToLower(ToAddress(account.PublicKey))] = account
+		}
+	}
+	return accounts, nil
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/erick785/services/common/log"
+	"github.com/erick785/services/common/signer"
+	"github.com/erick785/services/common/wallet"
+)
+
+var (
+	// trackInterval 订单交易状态检查间隔
+	trackInterval = 10 * time.Second
+	// confirmations 确认所需区块数
+	confirmations int64 = 6
+)
+
+// Tracker 跟踪已广播的订单交易: 内存池 -> 打包 -> 确认/失败/丢弃
+type Tracker struct {
+	db     *Mysql
+	wltdb  *wallet.Mysql
+	signer signer.Signer
+	nonces *NonceManager
+
+	BumpTimeout time.Duration // 未打包超时后提高燃料单价替换交易, 0 不替换
+	BumpPercent int64         // 燃料单价提高百分比
+}
+
+// NewTracker 创建订单交易跟踪器
+func NewTracker(db *Mysql, wltdb *wallet.Mysql, wltsigner signer.Signer, nonces *NonceManager, bumpTimeout time.Duration, bumpPercent int64) *Tracker {
+	return &Tracker{
+		db:          db,
+		wltdb:       wltdb,
+		signer:      wltsigner,
+		nonces:      nonces,
+		BumpTimeout: bumpTimeout,
+		BumpPercent: bumpPercent,
+	}
+}
+
+// Tracking 定时检查未完成的订单交易, 启动时即检查一次以恢复异常退出时遗留的待广播订单
+func (tracker *Tracker) Tracking(ctx context.Context) {
+	ticker := time.NewTicker(trackInterval)
+	defer ticker.Stop()
+	for first := true; ; first = false {
+		if !first {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+
+		pendings, err := tracker.db.GetOrdersByStatus(OrderPending)
+		if err != nil {
+			log.Errorf("[Tracking] GetOrdersByStatus --- %s", err)
+		}
+		for _, orderInfo := range pendings {
+			if err := tracker.reconcile(orderInfo); err != nil {
+				log.Errorf("[Tracking] pending order %s --- %s", orderInfo.ID, err)
+			}
+		}
+
+		curBlock, err := tracker.db.GetBlockChain()
+		if err != nil || curBlock == nil {
+			log.Errorf("[Tracking] GetBlockChain --- %v", err)
+			continue
+		}
+		orderInfos, err := tracker.db.GetOrdersByStatus(OrderSent, OrderMined)
+		if err != nil {
+			log.Errorf("[Tracking] GetOrdersByStatus --- %s", err)
+			continue
+		}
+		for _, orderInfo := range orderInfos {
+			if err := tracker.track(curBlock, orderInfo); err != nil {
+				log.Errorf("[Tracking] order %s --- %s", orderInfo.ID, err)
+			}
+		}
+	}
+}
+
+// reconcile 处理超过宽限期仍未广播的订单(落库后、广播前异常退出):
+// 交易已在节点时记为已广播, 序号已被其它交易占用时记为失败, 否则重新广播; 离线签名订单不重新广播
+func (tracker *Tracker) reconcile(orderInfo *OrderInfo) error {
+	if time.Now().Sub(time.Unix(orderInfo.Updated, 0)) < nonceGrace {
+		return nil
+	}
+	//与转账共用地址锁, 加锁后重新读取, 跳过正在广播的订单
+	tracker.nonces.Lock(orderInfo.From)
+	defer tracker.nonces.Unlock(orderInfo.From)
+	orderInfo, err := tracker.db.GetOrder(orderInfo.Phone, orderInfo.ID)
+	if err != nil || orderInfo == nil || orderInfo.Status != OrderPending {
+		return err
+	}
+
+	//离线签名订单由用户广播, 签名前没有交易哈希
+	offline := orderInfo.Type == OrderOffline
+	hash := orderInfo.Hash
+	if !offline {
+		if hash, err = RawTxHash(orderInfo.Raw); err != nil {
+			return err
+		}
+	}
+	var tx *Transaction
+	if len(hash) > 0 {
+		if tx, err = tracker.db.RPC.GetTransaction(hash); err != nil && !strings.Contains(err.Error(), "not found") {
+			return err
+		}
+	}
+	if tx == nil {
+		nonce, err := tracker.db.RPC.getTransactionCount(orderInfo.From, nil)
+		if err != nil {
+			return err
+		}
+		if nonce.Uint64() > orderInfo.Nonce {
+			log.Warnf("[Tracking] pending order %s failed, nonce %d < %d", orderInfo.ID, orderInfo.Nonce, nonce)
+			orderInfo.Status = OrderFailed
+			orderInfo.Error = "nonce used by another transaction"
+			return tracker.db.UpdateOrder(orderInfo)
+		}
+		if offline {
+			return tracker.expire(orderInfo)
+		}
+		if hash, err = tracker.db.RPC.SendRawTransaction(orderInfo.Raw); err != nil {
+			releaseOrder(tracker.nonces, orderInfo.ID, orderInfo.From, orderInfo.Nonce)
+			orderInfo.Status = OrderFailed
+			orderInfo.Error = err.Error()
+			return tracker.db.UpdateOrder(orderInfo)
+		}
+	}
+
+	log.Infof("[Tracking] pending order %s sent, hash %s", orderInfo.ID, hash)
+	if err := tracker.nonces.Commit(orderInfo.From, orderInfo.Nonce, hash); err != nil {
+		log.Errorf("[Tracking] order %s Commit nonce %d err %v", orderInfo.ID, orderInfo.Nonce, err)
+	}
+	orderInfo.Hash = hash
+	orderInfo.Status = OrderSent
+	orderInfo.Updated = time.Now().Unix()
+	return tracker.db.UpdateOrder(orderInfo)
+}
+
+// expire 未签名的离线订单在序号预留释放或过期后失败, 已签名的仍可能被广播, 保留至序号被占用
+func (tracker *Tracker) expire(orderInfo *OrderInfo) error {
+	if len(orderInfo.Hash) > 0 {
+		return nil
+	}
+	reserved, err := tracker.nonces.Reserved(orderInfo.From, orderInfo.Nonce)
+	if err != nil || reserved {
+		return err
+	}
+	log.Warnf("[Tracking] offline order %s expired, nonce %d released", orderInfo.ID, orderInfo.Nonce)
+	orderInfo.Status = OrderFailed
+	orderInfo.Error = "nonce released"
+	orderInfo.Updated = time.Now().Unix()
+	return tracker.db.UpdateOrder(orderInfo)
+}
+
+// findTx 查找订单当前或被替换的交易, 返回找到的交易及其哈希
+func (tracker *Tracker) findTx(orderInfo *OrderInfo) (*Transaction, string, error) {
+	hashes := append([]string{orderInfo.Hash}, orderInfo.Replaced...)
+	var pending *Transaction
+	var pendingHash string
+	for _, hash := range hashes {
+		tx, err := tracker.db.RPC.GetTransaction(hash)
+		if err != nil && !strings.Contains(err.Error(), "not found") {
+			return nil, "", err
+		}
+		if tx == nil {
+			continue
+		}
+		//已打包的交易优先
+		if tx.Height > 0 {
+			return tx, hash, nil
+		}
+		if pending == nil {
+			pending, pendingHash = tx, hash
+		}
+	}
+	return pending, pendingHash, nil
+}
+
+func (tracker *Tracker) track(curBlock *Block, orderInfo *OrderInfo) error {
+	status, height, hash := orderInfo.Status, orderInfo.Height, orderInfo.Hash
+	tx, txHash, err := tracker.findTx(orderInfo)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case tx == nil:
+		nonce, err := tracker.db.RPC.getTransactionCount(orderInfo.From, nil)
+		if err != nil {
+			return err
+		}
+		if nonce.Uint64() > orderInfo.Nonce {
+			//序号已被其它交易占用
+			log.Warnf("[Tracking] order %s dropped, nonce %d < %d", orderInfo.ID, orderInfo.Nonce, nonce)
+			orderInfo.Status = OrderDropped
+			orderInfo.Height = 0
+		} else if _, err := tracker.db.RPC.SendRawTransaction(orderInfo.Raw); err != nil {
+			log.Errorf("[Tracking] order %s rebroadcast --- %s", orderInfo.ID, err)
+		} else {
+			log.Infof("[Tracking] order %s rebroadcast %s", orderInfo.ID, orderInfo.Hash)
+			orderInfo.Status = OrderSent
+			orderInfo.Height = 0
+		}
+	case tx.Height == 0:
+		orderInfo.Status = OrderSent
+		orderInfo.Height = 0
+		//已取消的订单不再按原交易内容替换
+		if tracker.BumpTimeout > 0 && len(orderInfo.Cancel) == 0 && time.Now().Sub(time.Unix(orderInfo.Updated, 0)) > tracker.BumpTimeout {
+			if err := tracker.bump(orderInfo); err == errOrderChanged {
+				//订单已被加速或取消, 下次检查时重新读取
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	default:
+		orderInfo.Hash = txHash
+		orderInfo.Height = tx.Height
+		orderInfo.Status = OrderMined
+		if curBlock.Height-tx.Height+1 > confirmations {
+			if ok, err := tracker.db.RPC.GetTransactionStatus(txHash); err != nil {
+				return err
+			} else if ok && txHash == orderInfo.Cancel {
+				orderInfo.Status = OrderCancelled
+			} else if ok {
+				orderInfo.Status = OrderConfirmed
+			} else {
+				orderInfo.Status = OrderReverted
+			}
+		}
+	}
+
+	if status == orderInfo.Status && height == orderInfo.Height && hash == orderInfo.Hash {
+		return nil
+	}
+	log.Infof("[Tracking] order %s status %d -> %d, hash %s", orderInfo.ID, status, orderInfo.Status, orderInfo.Hash)
+	return tracker.db.UpdateOrder(orderInfo)
+}
+
+// errOrderChanged 订单在加锁前已被其它请求替换
+var errOrderChanged = errors.New("order changed")
+
+// bump 以相同序号、更高燃料单价替换未打包的交易并保存订单;
+// 与 /speeduptx、/canceltx 共用地址锁, 加锁后订单已变化时返回 errOrderChanged
+func (tracker *Tracker) bump(orderInfo *OrderInfo) error {
+	tracker.nonces.Lock(orderInfo.From)
+	defer tracker.nonces.Unlock(orderInfo.From)
+	if current, err := tracker.db.GetOrderByHash(orderInfo.Hash); err != nil {
+		return err
+	} else if current == nil || current.ID != orderInfo.ID || len(current.Cancel) > 0 {
+		return errOrderChanged
+	}
+
+	wlt, err := tracker.wltdb.GetWallet(orderInfo.Phone)
+	if err != nil {
+		return err
+	}
+	if wlt == nil {
+		return fmt.Errorf("wallet %s not found", orderInfo.Phone)
+	}
+	//口令地址没有口令无法重新签名, 返回 wallet.ErrPassphrase; 观察钱包返回 wallet.ErrWatchOnly
+	account, err := WalletAccount(tracker.signer, wlt, orderInfo.From, "")
+	if err != nil {
+		return err
+	}
+
+	gasPrice := new(big.Int).Div(new(big.Int).Mul(orderInfo.GasPrice, big.NewInt(100+tracker.BumpPercent)), big.NewInt(100))
+	to, value, data, err := OrderTx(orderInfo)
+	if err != nil {
+		return err
+	}
+	signedhash, err := CreateTx(account, orderInfo.Nonce, to, value, orderInfo.Gas, gasPrice, data)
+	if err != nil {
+		return err
+	}
+	raw := fmt.Sprintf("0x%s", signedhash)
+	hash, err := tracker.db.RPC.SendRawTransaction(raw)
+	if err != nil {
+		return err
+	}
+	log.Infof("[Tracking] order %s bump gas price %s -> %s, hash %s -> %s", orderInfo.ID, orderInfo.GasPrice, gasPrice, orderInfo.Hash, hash)
+
+	if err := tracker.nonces.Commit(orderInfo.From, orderInfo.Nonce, hash); err != nil {
+		log.Errorf("[Tracking] order %s Commit nonce %d err %v", orderInfo.ID, orderInfo.Nonce, err)
+	}
+	replaceOrder(orderInfo, hash, raw, orderInfo.Gas, gasPrice)
+	return tracker.db.UpdateOrder(orderInfo)
+}
@@ -27,6 +27,20 @@ type Transaction struct {
 	Size      int64    // 消耗 gasused
 	Signature string   // 签名
 	Fee       *big.Int // 消耗 eth
+	GasPrice  *big.Int // 燃料单价
+}
+
+// TxPayload 交易内容, 用于替换未打包的交易
+type TxPayload struct {
+	Hash     string   // 交易哈希
+	From     string   // 发送方
+	To       string   // 接收方, 部署合约时为空
+	Value    *big.Int // 转账金额
+	Data     []byte   // 调用数据
+	Nonce    uint64   // 交易序号
+	Gas      uint64   // 燃料大小
+	GasPrice *big.Int // 燃料单价
+	Height   int64    // 区块号, 未打包为 0
 }
 
 // AddressInfo 地址信息，
@@ -69,3 +83,60 @@ type TokenInfo struct {
 	Symbol  string `json:"symbol"`
 	Decimal int64  `json:"decimal"`
 }
+
+// NonceInfo 已分配的交易序号
+type NonceInfo struct {
+	Address string `json:"address"` // 账户地址
+	Nonce   uint64 `json:"nonce"`   // 交易序号
+	Hash    string `json:"hash"`    // 交易哈希(未广播为空)
+	Time    int64  `json:"time"`    // 分配时间
+}
+
+// OrderInfo 订单信息
+type OrderInfo struct {
+	ID           string   `json:"id"`            // 订单号
+	Phone        string   `json:"phone"`         // 用户标识
+	Type         int      `json:"type"`          // 订单类型
+	From         string   `json:"from"`          // 发送方
+	TokenAddress string   `json:"token_address"` // token 地址
+	To           string   `json:"to"`            // 接收方
+	Value        *big.Int `json:"value"`         // 接收金额
+	Data         string   `json:"data"`          // 调用数据(合约调用/部署)
+	Gas          uint64   `json:"gas"`           // 燃料大小
+	GasPrice     *big.Int `json:"gas_price"`     // 燃料单价
+	Fee          *big.Int `json:"fee"`           // 手续费
+	Nonce        uint64   `json:"nonce"`         // 交易序号
+	Raw          string   `json:"-"`             // 签名交易
+	Hash         string   `json:"hash"`          // 交易哈希
+	Replaced     []string `json:"replaced"`      // 被替换的交易哈希
+	Cancel       string   `json:"cancel"`        // 取消交易哈希
+	Height       int64    `json:"height"`        // 交易所在区块高度
+	Status       int      `json:"status"`        // 订单状态
+	Error        string   `json:"error"`         // 错误信息
+	Time         int64    `json:"time"`          // 创建时间
+	Updated      int64    `json:"updated"`       // 最近广播时间
+}
+
+// ScheduleInfo 定时转账计划
+type ScheduleInfo struct {
+	ID           string   `json:"id"`            // 计划号
+	Phone        string   `json:"phone"`         // 用户标识
+	TokenAddress string   `json:"token_address"` // token 地址
+	To           string   `json:"to"`            // 接收方
+	Value        *big.Int `json:"value"`         // 接收金额
+	Gas          uint64   `json:"gas"`           // 燃料大小, 0 使用默认值
+	GasPrice     *big.Int `json:"gas_price"`     // 燃料单价, 0 使用节点价格
+	Cron         string   `json:"cron"`          // 执行周期, 为空时只执行一次
+	Next         int64    `json:"next"`          // 下次执行时间
+	Count        int64    `json:"count"`         // 最多执行次数, 0 不限制
+	Runs         int64    `json:"runs"`          // 已执行次数
+	Policy       string   `json:"policy"`        // 余额不足处理策略
+	Retries      int64    `json:"retries"`       // 本次已重试次数
+	MaxRetries   int64    `json:"max_retries"`   // 最多重试次数
+	Status       int      `json:"status"`        // 计划状态
+	LastOrder    string   `json:"last_order"`    // 最近执行的订单号
+	LastHash     string   `json:"last_hash"`     // 最近执行的交易哈希
+	LastError    string   `json:"last_error"`    // 最近执行的错误信息
+	Time         int64    `json:"time"`          // 创建时间
+	Updated      int64    `json:"updated"`       // 更新时间
+}
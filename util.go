@@ -2,9 +2,14 @@ package main
 
 import (
 	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 
+	"github.com/erick785/services/common/abi"
+	"github.com/erick785/services/common/signer"
 	"github.com/erick785/services/common/wallet"
 	"github.com/erick785/uranus/common/crypto"
 	"github.com/erick785/uranus/common/rlp"
@@ -14,8 +19,19 @@ import (
 
 var (
 	COINTYPE uint32 = 60
+	// COINDECIMAL 原生币精度
+	COINDECIMAL int64 = 18
+
+	// TransferGas token 转账默认燃料大小
+	TransferGas int64 = 100000
+
+	// ZeroAddress 未创建钱包时估算手续费使用的发送方
+	ZeroAddress = "0x0000000000000000000000000000000000000000"
 )
 
+// transferMethodID transfer(address,uint256)
+var transferMethodID = []byte{0xa9, 0x05, 0x9c, 0xbb}
+
 // ToAddress
 func ToAddress(pubKey *ecdsa.PublicKey) string {
 	return crypto.PubkeyToAddress(*pubKey).String()
@@ -27,24 +43,404 @@ func ValidAddress(addr string) bool {
 }
 
 func ParseDerivationPath(coinType uint32) wallet.DerivationPath {
-	path, err := wallet.ParseDerivationPath(fmt.Sprintf("m/44'/%d'/0'/0/0", coinType))
+	return AddressDerivationPath(coinType, 0, 0)
+}
+
+// AddressDerivationPath BIP44 地址路径 m/44'/coin'/account'/0/index
+func AddressDerivationPath(coinType uint32, account uint32, index uint32) wallet.DerivationPath {
+	path, err := wallet.ParseDerivationPath(fmt.Sprintf("m/44'/%d'/%d'/0/%d", coinType, account, index))
 	if err != nil {
 		panic(err)
 	}
 	return path
 }
 
-func CreateTx(privKey *ecdsa.PrivateKey, nonce uint64, to string, value *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) (string, error) {
-	tto := utils.HexToAddress(to)
-	tx := types.NewTransaction(types.Binary, nonce, value, gasLimit, gasPrice, data, []*utils.Address{&tto}...)
-	if err := tx.SignTx(types.Signer{}, privKey); err != nil {
+// DefaultAccount 用户默认地址的签名账户, 设置了口令时为口令下的默认地址, 需要提供口令
+func DefaultAccount(wltsigner signer.Signer, wlt *wallet.Wallet, passphrase string) (*signer.Account, error) {
+	if wlt.WatchOnly() {
+		return nil, wallet.ErrWatchOnly
+	}
+	if id := wlt.Passphrase(); len(id) > 0 {
+		return WalletAccount(wltsigner, wlt, id, passphrase)
+	}
+	if len(passphrase) > 0 {
+		return nil, wallet.ErrPassphrase
+	}
+	return signer.NewAccount(wltsigner, wlt.Name, ParseDerivationPath(COINTYPE).String(), "")
+}
+
+// WalletAccount 查找用户地址对应的签名账户, 包括 HD 派生地址、口令地址及导入的私钥;
+// 口令地址以 passphrase 派生, 与记录的地址不符时返回 wallet.ErrPassphrase; 观察钱包返回 wallet.ErrWatchOnly
+func WalletAccount(wltsigner signer.Signer, wlt *wallet.Wallet, address string, passphrase string) (*signer.Account, error) {
+	if wlt.WatchOnly() {
+		return nil, wallet.ErrWatchOnly
+	}
+	//已派生的地址直接取路径
+	if path, ok := wlt.AddressPath(address); ok {
+		return signer.NewAccount(wltsigner, wlt.Name, path.String(), "")
+	}
+	for _, index := range wlt.AddressIndexes() {
+		path := AddressDerivationPath(COINTYPE, index.Account, index.Index).String()
+		if len(index.Passphrase) > 0 {
+			if !strings.EqualFold(index.Address, address) {
+				continue
+			}
+			account, err := signer.NewAccount(wltsigner, wlt.Name, path, passphrase)
+			if err != nil {
+				return nil, err
+			}
+			if !strings.EqualFold(ToAddress(account.PublicKey), address) {
+				return nil, wallet.ErrPassphrase
+			}
+			return account, nil
+		}
+		indexAddress, err := IndexAddress(wltsigner, wlt, index)
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(indexAddress, address) {
+			return signer.NewAccount(wltsigner, wlt.Name, path, "")
+		}
+	}
+	for _, key := range wlt.ImportedKeys() {
+		if strings.EqualFold(key.Address, address) {
+			return signer.NewAccount(wltsigner, wlt.Name, key.Address, "")
+		}
+	}
+	return nil, fmt.Errorf("address %s not found in wallet %s", address, wlt.Name)
+}
+
+// FromAccount 转出地址的签名账户, 为空时为默认地址
+func FromAccount(wltsigner signer.Signer, wlt *wallet.Wallet, from string, passphrase string) (*signer.Account, error) {
+	if len(from) == 0 {
+		return DefaultAccount(wltsigner, wlt, passphrase)
+	}
+	return WalletAccount(wltsigner, wlt, from, passphrase)
+}
+
+// DefaultAddress 用户默认地址(小写), 设置了口令时为口令下的默认地址
+func DefaultAddress(wltsigner signer.Signer, wlt *wallet.Wallet) (string, error) {
+	if id := wlt.Passphrase(); len(id) > 0 {
+		return id, nil
+	}
+	return pathAddress(wltsigner, wlt, walletPath(wlt, 0, 0))
+}
+
+// walletPath 钱包账户下的地址路径; 观察钱包只有扩展公钥对应的一个账户, 账户 0 即该账户
+func walletPath(wlt *wallet.Wallet, account uint32, index uint32) wallet.DerivationPath {
+	if wlt.WatchOnly() {
+		account = wlt.WatchAccount()
+	}
+	return AddressDerivationPath(COINTYPE, account, index)
+}
+
+// feeAddress 估算手续费的发送方, 用户尚无钱包时使用零地址而不创建钱包
+func feeAddress(wltsigner signer.Signer, wlt *wallet.Wallet) (string, error) {
+	if wlt == nil {
+		return ZeroAddress, nil
+	}
+	return DefaultAddress(wltsigner, wlt)
+}
+
+// IndexAddress 地址索引对应的地址(小写), 口令地址取分配时记录的地址
+func IndexAddress(wltsigner signer.Signer, wlt *wallet.Wallet, index *wallet.AddressIndex) (string, error) {
+	if len(index.Passphrase) > 0 {
+		return index.Address, nil
+	}
+	return pathAddress(wltsigner, wlt, walletPath(wlt, index.Account, index.Index))
+}
+
+// pathAddress 派生路径对应的地址(小写), 先查地址缓存; 观察钱包由扩展公钥派生, 不经过签名服务
+func pathAddress(wltsigner signer.Signer, wlt *wallet.Wallet, path wallet.DerivationPath) (string, error) {
+	if address, ok := wlt.Address(path.String()); ok {
+		return address, nil
+	}
+	var pub *ecdsa.PublicKey
+	var err error
+	if wlt.WatchOnly() {
+		pub, err = wlt.DerivePublicKey(path)
+	} else {
+		pub, err = wltsigner.PublicKey(wlt.Name, path.String(), "")
+	}
+	if err != nil {
 		return "", err
 	}
+	address := strings.ToLower(ToAddress(pub))
+	wlt.SetAddress(path.String(), address)
+	return address, nil
+}
 
-	txb, err := rlp.EncodeToBytes(tx)
+// InsertOrGetWallet 查找用户钱包, 没有时由签名方以新生成的商创建; 使用签名服务时 API 进程只读取 meta
+func InsertOrGetWallet(wltdb *wallet.Mysql, wltsigner signer.Signer, name string) (*wallet.Wallet, error) {
+	wlt, err := wltdb.GetWallet(name)
+	if wlt != nil || err != nil {
+		return wlt, err
+	}
+	if err := wltsigner.CreateWallet(name); err != nil {
+		return nil, err
+	}
+	if wlt, err = wltdb.GetWallet(name); wlt == nil && err == nil {
+		return nil, fmt.Errorf("wallet %s not created", name)
+	}
+	return wlt, err
+}
+
+// ImportWallet 由签名方以指定的商导入用户钱包; 覆盖已有钱包时先以 idleWallet 判断原地址能否替换,
+// 商不同且不能替换时返回 wallet.ErrWalletInUse
+func ImportWallet(db *Mysql, wltdb *wallet.Mysql, wltsigner signer.Signer, name string, hexEntropy string, overwrite bool) (*wallet.Wallet, error) {
+	old, err := wltdb.GetWallet(name)
+	if err != nil {
+		return nil, err
+	}
+	replace := false
+	if old != nil && overwrite {
+		defer old.Zero()
+		if replace, err = idleWallet(db, wltsigner, old); err != nil {
+			return nil, err
+		}
+	}
+	if err := wltsigner.ImportWallet(name, hexEntropy, overwrite, replace); err != nil {
+		return nil, err
+	}
+	//地址随商改变, 清除 API 进程的地址缓存
+	wltdb.Cache.Remove(name)
+	return wltdb.GetWallet(name)
+}
+
+// RenameWallet 由签名方修改用户名(商以新用户名重新加密), 并清除 API 进程的地址缓存
+func RenameWallet(wltdb *wallet.Mysql, wltsigner signer.Signer, name string, newname string) error {
+	if err := wltsigner.RenameWallet(name, newname); err != nil {
+		return err
+	}
+	wltdb.Cache.Remove(name)
+	wltdb.Cache.Remove(newname)
+	return nil
+}
+
+// untouchedWallet 钱包是否从未使用(如查询接口自动创建): 只有默认地址, 没有口令、导入私钥及订单,
+// 默认地址没有余额、交易记录及 token
+func untouchedWallet(db *Mysql, wltsigner signer.Signer, wlt *wallet.Wallet) (bool, error) {
+	if len(wlt.AddressIndexes()) > 1 || len(wlt.ImportedKeys()) > 0 || len(wlt.Passphrase()) > 0 {
+		return false, nil
+	}
+	if count, err := db.CountOrders(wlt.Name); err != nil || count > 0 {
+		return false, err
+	}
+	address, err := DefaultAddress(wltsigner, wlt)
+	if err != nil {
+		return false, err
+	}
+	if amount, err := db.RPC.getBalance(address, "", nil); err != nil || amount.Sign() > 0 {
+		return false, err
+	}
+	//最近 1 笔交易(参数依次为数量、页号)
+	if txs, err := db.GetTransactionsByAddress(address, 1, 0); err != nil || len(txs) > 0 {
+		return false, err
+	}
+	if tokens, err := db.GetTokenInfosByAddress(address); err != nil || len(tokens) > 0 {
+		return false, err
+	}
+	return true, nil
+}
+
+// idleWallet 钱包的商能否替换: 没有未完成(待广播、已广播)的订单, 由商派生的地址(包括口令地址)没有余额及 token 余额;
+// 导入的私钥与商无关, 替换后仍保留, 不检查
+func idleWallet(db *Mysql, wltsigner signer.Signer, wlt *wallet.Wallet) (bool, error) {
+	if count, err := db.CountOrders(wlt.Name, OrderPending, OrderSent); err != nil || count > 0 {
+		return false, err
+	}
+	for _, index := range wlt.AddressIndexes() {
+		address, err := IndexAddress(wltsigner, wlt, index)
+		if err != nil {
+			return false, err
+		}
+		if amount, err := db.RPC.getBalance(address, "", nil); err != nil || amount.Sign() > 0 {
+			return false, err
+		}
+		tokens, err := db.GetTokenInfosByAddress(address)
+		if err != nil {
+			return false, err
+		}
+		for _, token := range tokens {
+			if amount, err := db.RPC.getBalance(address, token.Address, nil); err != nil || amount.Sign() > 0 {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// AllocateAddress 新分配地址索引对应的地址; 口令地址先以默认地址校验口令, 再派生并记录到索引
+func AllocateAddress(wltsigner signer.Signer, wlt *wallet.Wallet, index *wallet.AddressIndex, passphrase string) (string, error) {
+	if len(index.Passphrase) == 0 {
+		return IndexAddress(wltsigner, wlt, index)
+	}
+	if _, err := WalletAccount(wltsigner, wlt, index.Passphrase, passphrase); err != nil {
+		return "", err
+	}
+	pub, err := wltsigner.PublicKey(wlt.Name, AddressDerivationPath(COINTYPE, index.Account, index.Index).String(), passphrase)
+	if err != nil {
+		return "", err
+	}
+	index.Address = strings.ToLower(ToAddress(pub))
+	return index.Address, nil
+}
+
+// keystorePath 导出私钥的派生路径, 为空时为默认地址路径
+func keystorePath(path string) (string, error) {
+	if len(path) == 0 {
+		return ParseDerivationPath(COINTYPE).String(), nil
+	}
+	p, err := wallet.ParseDerivationPath(path)
+	if err != nil {
+		return "", err
+	}
+	return p.String(), nil
+}
+
+// WalletAddresses 用户已分配的全部地址, 默认地址在前, 导入私钥的地址在后
+func WalletAddresses(wltsigner signer.Signer, wlt *wallet.Wallet) ([]string, error) {
+	addresses := []string{}
+	for _, index := range wlt.AddressIndexes() {
+		address, err := IndexAddress(wltsigner, wlt, index)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	for _, key := range wlt.ImportedKeys() {
+		addresses = append(addresses, key.Address)
+	}
+	return addresses, nil
+}
+
+// NewTx 创建未签名交易, to 为空时为合约部署
+func NewTx(nonce uint64, to string, value *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *types.Transaction {
+	tos := []*utils.Address{}
+	if len(to) > 0 {
+		tto := utils.HexToAddress(to)
+		tos = append(tos, &tto)
+	}
+	return types.NewTransaction(types.Binary, nonce, value, gasLimit, gasPrice, data, tos...)
+}
+
+// CreateTx 创建并签名交易, to 为空时为合约部署
+func CreateTx(account *signer.Account, nonce uint64, to string, value *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) (string, error) {
+	txb, err := rlp.EncodeToBytes(NewTx(nonce, to, value, gasLimit, gasPrice, data))
 	if err != nil {
 		return "", err
 	}
 
+	signed, err := account.SignTx(txb)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.BytesToHex(signed), nil
+}
+
+// BuildTx 创建未签名交易, 返回 RLP 编码
+func BuildTx(nonce uint64, to string, value *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) (string, error) {
+	txb, err := rlp.EncodeToBytes(NewTx(nonce, to, value, gasLimit, gasPrice, data))
+	if err != nil {
+		return "", err
+	}
 	return utils.BytesToHex(txb), nil
 }
+
+// DecodeTx 解码 RLP 编码的交易
+func DecodeTx(raw string) (*types.Transaction, error) {
+	txb, err := hex.DecodeString(strings.TrimPrefix(raw, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if len(txb) == 0 {
+		return nil, errors.New("empty transaction")
+	}
+	tx := &types.Transaction{}
+	if err := rlp.DecodeBytes(txb, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// RawTxHash 已签名交易的哈希
+func RawTxHash(raw string) (string, error) {
+	tx, err := DecodeTx(raw)
+	if err != nil {
+		return "", err
+	}
+	return tx.Hash().String(), nil
+}
+
+// SignRawTx 签名 BuildTx 创建的交易
+func SignRawTx(account *signer.Account, raw string) (string, error) {
+	tx, err := DecodeTx(raw)
+	if err != nil {
+		return "", err
+	}
+
+	txb, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := account.SignTx(txb)
+	if err != nil {
+		return "", err
+	}
+
+	return utils.BytesToHex(signed), nil
+}
+
+// ValidValue 转账金额需为非负且不超过 uint256
+func ValidValue(value *big.Int) error {
+	if value.Sign() < 0 || value.BitLen() > 256 {
+		return fmt.Errorf("invalid value %v", value)
+	}
+	return nil
+}
+
+// TransferData 构造 token transfer(address _to, uint256 _value) 调用数据
+func TransferData(to string, value *big.Int) ([]byte, error) {
+	if err := ValidValue(value); err != nil {
+		return nil, err
+	}
+	tto := utils.HexToAddress(to)
+	data := make([]byte, 4+32+32)
+	copy(data, transferMethodID)
+	copy(data[4+32-len(tto.Bytes()):4+32], tto.Bytes())
+	vb := value.Bytes()
+	copy(data[len(data)-len(vb):], vb)
+	return data, nil
+}
+
+// ContractData 合约调用数据: 十六进制 data, 或方法签名+参数编码;
+// 部署时 data 为合约字节码, method 为构造函数参数类型
+func ContractData(hexData string, method string, args []string, deploy bool) ([]byte, error) {
+	data, err := hex.DecodeString(strings.TrimPrefix(hexData, "0x"))
+	if err != nil {
+		return nil, err
+	}
+	if deploy && len(data) == 0 {
+		return nil, errors.New("empty contract bytecode")
+	}
+	if len(method) == 0 {
+		return data, nil
+	}
+	if !deploy {
+		if len(data) > 0 {
+			return nil, errors.New("data and method are exclusive")
+		}
+		return abi.PackMethod(method, args)
+	}
+	_, types, err := abi.ParseSignature(method)
+	if err != nil {
+		return nil, err
+	}
+	params, err := abi.Pack(types, args)
+	if err != nil {
+		return nil, err
+	}
+	return append(data, params...), nil
+}
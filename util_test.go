@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/erick785/services/common/wallet"
+)
+
+var limitRegex = regexp.MustCompile(`limit (-?\d+), (-?\d+)`)
+
+func TestUntouchedWallet(t *testing.T) {
+	address := "0x00000000000000000000000000000000000000aa"
+	for _, tc := range []struct {
+		name    string
+		orders  int64
+		balance string
+		history int
+		want    bool
+	}{
+		{"new", 0, "0x0", 0, true},
+		{"orders", 1, "0x0", 0, false},
+		{"balance", 0, "0x1", 0, false},
+		//只在数据库中有交易记录, 内存区块为空
+		{"db history", 0, "0x0", 1, false},
+		{"db history page", 0, "0x0", 3, false},
+	} {
+		rpc := newFakeRPC(tc.balance)
+		db := newFakeMysql(t, func(query string) ([]string, [][]driver.Value, error) {
+			switch {
+			case strings.Contains(query, "COUNT(*) FROM t_order"):
+				return []string{"count"}, [][]driver.Value{{tc.orders}}, nil
+			case strings.Contains(query, "FROM t_history"):
+				//同 MySQL: limit 偏移, 数量
+				m := limitRegex.FindStringSubmatch(query)
+				skip, _ := strconv.Atoi(m[1])
+				count, _ := strconv.Atoi(m[2])
+				if skip < 0 || count < 0 {
+					return nil, nil, fmt.Errorf("invalid limit %d, %d", skip, count)
+				}
+				rows := [][]driver.Value{}
+				for i := skip; i < tc.history && i < skip+count; i++ {
+					rows = append(rows, []driver.Value{fmt.Sprintf("0x%02x", i)})
+				}
+				return []string{"s_hash"}, rows, nil
+			case strings.Contains(query, "FROM t_transaction"):
+				return []string{"s_hash", "s_ins", "s_outs", "i_created", "i_height", "s_fee", "i_size"},
+					[][]driver.Value{{"0x00", "[]", "[]", int64(1), int64(1), "0", int64(0)}}, nil
+			}
+			return nil, nil, nil
+		}, rpc.URL)
+
+		wlt, err := wallet.NewWallet("user", wallet.NewHexEntropy(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//默认地址已派生, 不经过签名服务
+		wlt.SetAddress(walletPath(wlt, 0, 0).String(), address)
+		if ok, err := untouchedWallet(db, nil, wlt); err != nil || ok != tc.want {
+			t.Errorf("%s: untouched %v %v, expected %v", tc.name, ok, err, tc.want)
+		}
+		rpc.Close()
+	}
+}